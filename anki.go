@@ -0,0 +1,357 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// ankiNote is one flashcard of an Anki export
+type ankiNote struct {
+	GUID  string   // stable identity, so re-imports update the note
+	Front string   // question side, HTML
+	Back  string   // answer side, HTML
+	Tags  []string // Anki tags: the command's tags and its complexity
+	Deck  string   // deck the note goes in, named after its sheet
+}
+
+// ankiID derives a stable Anki ID from parts: positive, and small enough for
+// the JavaScript numbers Anki's JSON is read with
+func ankiID(parts ...string) int64 {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return int64(binary.BigEndian.Uint64(sum[:8])>>12) | 1<<40
+}
+
+// ankiGUID derives the stable GUID of a note from parts
+func ankiGUID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(sum[:9])
+}
+
+// ankiTag makes a tag Anki accepts, which can't hold spaces
+func ankiTag(tag string) string {
+	return strings.Join(strings.Fields(tag), "_")
+}
+
+// ankiHTML escapes text for a note field, keeping its line breaks
+func ankiHTML(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// ankiCode renders code for a note field
+func ankiCode(code string) string {
+	return "<pre><code>" + html.EscapeString(code) + "</code></pre>"
+}
+
+// ankiNotes makes the notes of a sheet: one asking for each command by its
+// short description, and one asking for each described example. GUIDs come
+// from the sheet's annotationKey, the command name and the example's
+// position, so retitling the sheet or fixing a command's text updates the
+// notes, while renaming the command or moving the sheet makes new ones.
+func ankiNotes(sheet CheatSheet) []ankiNote {
+	key := annotationKey(sheet)
+	var notes []ankiNote
+	seen := make(map[string]bool)
+	add := func(note ankiNote) {
+		if !seen[note.GUID] {
+			seen[note.GUID] = true
+			notes = append(notes, note)
+		}
+	}
+	for _, cmd := range sheet.Commands {
+		var tags []string
+		for _, tag := range cmd.Tags {
+			tags = append(tags, ankiTag(tag))
+		}
+		if level, _ := parseComplexity(cmd.Complexity); level != complexityUnset {
+			tags = append(tags, "complexity::"+level.String())
+		}
+
+		if cmd.ShortDesc != "" && cmd.Syntax != "" {
+			add(ankiNote{
+				GUID:  ankiGUID(key, cmd.Name),
+				Front: ankiHTML(cmd.ShortDesc),
+				Back:  ankiCode(cmd.Syntax),
+				Tags:  tags,
+				Deck:  sheet.Title,
+			})
+		}
+		for i, ex := range cmd.Examples {
+			if ex.Description == "" || ex.Code == "" {
+				continue
+			}
+			back := ankiCode(ex.Code)
+			if ex.Output != "" {
+				back += "<pre><samp>" + html.EscapeString(ex.Output) + "</samp></pre>"
+			}
+			add(ankiNote{
+				GUID:  ankiGUID(key, cmd.Name, strconv.Itoa(i)),
+				Front: ankiHTML(ex.Description),
+				Back:  back,
+				Tags:  tags,
+				Deck:  sheet.Title,
+			})
+		}
+	}
+	return notes
+}
+
+// exportAnkiTSV writes notes as a file for Anki's text import. The header
+// lines tell Anki the columns, so the GUID column lets re-imports update the
+// notes they made before.
+func exportAnkiTSV(notes []ankiNote) []byte {
+	var b bytes.Buffer
+	b.WriteString("#separator:tab\n#html:true\n#notetype:Basic\n#guid column:1\n#tags column:4\n#deck column:5\n")
+	field := strings.NewReplacer("\t", "&#9;", "\n", "<br>")
+	for _, note := range notes {
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%s\n", note.GUID, field.Replace(note.Front), field.Replace(note.Back),
+			strings.Join(note.Tags, " "), field.Replace(note.Deck))
+	}
+	return b.Bytes()
+}
+
+// ankiModel is the note type of the exported notes: Anki's Basic, front and
+// back, under a name and ID of its own
+var ankiModel = map[string]any{
+	"name":  "cheatcheat",
+	"type":  0,
+	"sortf": 0,
+	"usn":   -1,
+	"tags":  []any{},
+	"vers":  []any{},
+	"flds": []any{
+		map[string]any{"name": "Front", "ord": 0, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}},
+		map[string]any{"name": "Back", "ord": 1, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}},
+	},
+	"tmpls": []any{map[string]any{
+		"name": "Card 1", "ord": 0, "did": nil, "bqfmt": "", "bafmt": "",
+		"qfmt": "{{Front}}",
+		"afmt": "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+	}},
+	"css":       ".card { font-family: arial; font-size: 20px; text-align: center; }\npre { text-align: left; }\n",
+	"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\begin{document}\n",
+	"latexPost": "\\end{document}",
+	"req":       []any{[]any{0, "any", []any{0}}},
+}
+
+// ankiDeckConfig is the default options group of the exported decks
+var ankiDeckConfig = map[string]any{
+	"id": 1, "name": "Default", "usn": 0, "mod": 0, "dyn": false,
+	"maxTaken": 60, "timer": 0, "autoplay": true, "replayq": true,
+	"new":   map[string]any{"delays": []any{1, 10}, "ints": []any{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+	"lapse": map[string]any{"delays": []any{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+	"rev":   map[string]any{"perDay": 100, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "bury": true},
+}
+
+// ankiDeck returns the deck named name in the JSON of Anki's collection
+func ankiDeck(id int64, name string, mod int64) map[string]any {
+	return map[string]any{
+		"id": id, "name": name, "mod": mod, "usn": -1, "desc": "", "dyn": 0, "conf": 1,
+		"collapsed": false, "browserCollapsed": false, "extendNew": 10, "extendRev": 50,
+		"newToday": []any{0, 0}, "revToday": []any{0, 0}, "lrnToday": []any{0, 0}, "timeToday": []any{0, 0},
+	}
+}
+
+// ankiSchema creates the tables and indexes of an Anki collection, schema
+// version 11
+var ankiSchema = []string{
+	"CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, " +
+		"scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, " +
+		"conf text not null, models text not null, decks text not null, dconf text not null, tags text not null)",
+	"CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, " +
+		"mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, " +
+		"csum integer not null, flags integer not null, data text not null)",
+	"CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, " +
+		"ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, " +
+		"due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, " +
+		"left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null)",
+	"CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, " +
+		"ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, " +
+		"time integer not null, type integer not null)",
+	"CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)",
+	"CREATE INDEX ix_notes_usn on notes (usn)",
+	"CREATE INDEX ix_cards_usn on cards (usn)",
+	"CREATE INDEX ix_revlog_usn on revlog (usn)",
+	"CREATE INDEX ix_cards_nid on cards (nid)",
+	"CREATE INDEX ix_cards_sched on cards (did, queue, due)",
+	"CREATE INDEX ix_revlog_cid on revlog (cid)",
+	"CREATE INDEX ix_notes_csum on notes (csum)",
+}
+
+// ankiCollection creates an Anki collection holding the rows of each table
+// and returns the database file
+func ankiCollection(rows map[string][][]any) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "cheatcheat-anki-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "collection.anki2")
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	for _, stmt := range ankiSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"col", "notes", "cards"} {
+		for _, row := range rows[table] {
+			insert := "INSERT INTO " + table + " VALUES (?" + strings.Repeat(", ?", len(row)-1) + ")"
+			if _, err := tx.Exec(insert, row...); err != nil {
+				return nil, fmt.Errorf("%s: %w", table, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// htmlMarkup matches markup, stripped from the sort field before checksumming
+var htmlMarkup = regexp.MustCompile(`<[^>]*>`)
+
+// exportAnkiPackage writes notes as an Anki package: a zip holding the
+// collection, an SQLite database, and its (empty) media list
+func exportAnkiPackage(notes []ankiNote, now time.Time) ([]byte, error) {
+	mod := now.Unix()
+	modelID := ankiID("cheatcheat note type")
+	model := map[string]any{"id": modelID, "mod": mod}
+	for k, v := range ankiModel {
+		model[k] = v
+	}
+
+	decks := map[string]any{"1": ankiDeck(1, "Default", mod)}
+	var notesRows, cardsRows [][]any
+	for i, note := range notes {
+		deckID := ankiID("deck", note.Deck)
+		decks[strconv.FormatInt(deckID, 10)] = ankiDeck(deckID, note.Deck, mod)
+		model["did"] = deckID
+
+		noteID := ankiID("note", note.GUID)
+		sortField := html.UnescapeString(htmlMarkup.ReplaceAllString(note.Front, ""))
+		sum := sha1.Sum([]byte(sortField))
+		checksum := int64(binary.BigEndian.Uint32(sum[:4]))
+		tags := ""
+		if len(note.Tags) > 0 {
+			tags = " " + strings.Join(note.Tags, " ") + " "
+		}
+		notesRows = append(notesRows, []any{noteID, note.GUID, modelID, mod, int64(-1), tags,
+			note.Front + "\x1f" + note.Back, sortField, checksum, int64(0), ""})
+		// New cards, in sheet order
+		cardsRows = append(cardsRows, []any{noteID, noteID, deckID, int64(0), mod, int64(-1), int64(0), int64(0),
+			int64(i + 1), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), ""})
+	}
+
+	conf := map[string]any{
+		"nextPos": len(notes) + 1, "estTimes": true, "activeDecks": []any{1}, "sortType": "noteFld",
+		"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": 1, "newSpread": 0,
+		"dueCounts": true, "curModel": modelID, "collapseTime": 1200,
+	}
+	var jsonErr error
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			jsonErr = err
+		}
+		return string(data)
+	}
+	col := []any{int64(1), mod, mod * 1000, mod * 1000, int64(11), int64(0), int64(0), int64(0),
+		encode(conf), encode(map[string]any{strconv.FormatInt(modelID, 10): model}), encode(decks),
+		encode(map[string]any{"1": ankiDeckConfig}), "{}"}
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	collection, err := ankiCollection(map[string][][]any{"col": {col}, "notes": notesRows, "cards": cardsRows})
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	archive := zip.NewWriter(&b)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{"collection.anki2", collection}, {"media", []byte("{}")}} {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// runExportAnki implements "cheatcheat export anki": flashcards for Anki, as
+// a file for its text import or, when writing to a .apkg file, a package
+func runExportAnki(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export anki", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("o", "", "file to write, a package when it ends in .apkg; standard output otherwise")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat export anki [-o file.tsv|file.apkg] [paths...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	sheets, err := loadExportSheets(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var notes []ankiNote
+	for _, sheet := range sheets {
+		notes = append(notes, ankiNotes(sheet)...)
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(*out), ".apkg") {
+		data, err = exportAnkiPackage(notes, time.Now())
+	} else {
+		data = exportAnkiTSV(notes)
+	}
+	if err == nil {
+		err = writeExport(data, *out, stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ankiSheet() CheatSheet {
+	return CheatSheet{Title: "Git", Path: filepath.Join("cheatsheets", "git.yaml"), Commands: []Command{
+		{Name: "git status", ShortDesc: "Show the working tree status", Syntax: "git status [-s]",
+			Tags: []string{"basics", "day one"}, Complexity: "Beginner",
+			Examples: []Example{
+				{Code: "git status -s", Description: "Short\tformat", Output: "M <file>"},
+				{Code: "git status -s", Description: "The same code again"},
+				{Code: "git status --ignored"},
+			}},
+		{Name: "git help", Syntax: "git help <command>"},
+	}}
+}
+
+func TestAnkiNotes(t *testing.T) {
+	notes := ankiNotes(ankiSheet())
+	if len(notes) != 3 {
+		t.Fatalf("expected a note for the command and one for each described example, got %d", len(notes))
+	}
+	if want := []string{"basics", "day_one", "complexity::beginner"}; !reflect.DeepEqual(notes[0].Tags, want) {
+		t.Errorf("got tags %v, want %v", notes[0].Tags, want)
+	}
+	if notes[1].Back != "<pre><code>git status -s</code></pre><pre><samp>M &lt;file&gt;</samp></pre>" {
+		t.Errorf("got back %q", notes[1].Back)
+	}
+
+	// The title, descriptions and code can change without changing the
+	// notes' identity
+	edited := ankiSheet()
+	edited.Title = "Git Basics"
+	edited.Commands[0].ShortDesc = "Show what changed"
+	edited.Commands[0].Syntax = "git status [-s] [-b]"
+	edited.Commands[0].Examples[0].Code = "git status --short"
+	again := ankiNotes(edited)
+	for i := range notes {
+		if again[i].GUID != notes[i].GUID {
+			t.Errorf("expected note %d to keep GUID %s, got %s", i, notes[i].GUID, again[i].GUID)
+		}
+	}
+	if notes[0].GUID == notes[1].GUID || notes[1].GUID == notes[2].GUID {
+		t.Error("expected each note to have its own GUID")
+	}
+
+	// A sheet elsewhere makes notes of its own
+	moved := ankiSheet()
+	moved.Path = filepath.Join("cheatsheets", "work", "git.yaml")
+	if ankiNotes(moved)[0].GUID == notes[0].GUID {
+		t.Error("expected another sheet's notes to have other GUIDs")
+	}
+}
+
+func TestExportAnkiTSV(t *testing.T) {
+	notes := ankiNotes(ankiSheet())
+	lines := strings.Split(string(exportAnkiTSV(notes)), "\n")
+	if lines[0] != "#separator:tab" || !strings.HasPrefix(lines[3], "#guid column:1") {
+		t.Errorf("expected Anki's header lines, got %q", lines[:6])
+	}
+	want := notes[1].GUID + "\tShort&#9;format\t<pre><code>git status -s</code></pre><pre><samp>M &lt;file&gt;</samp></pre>\tbasics day_one complexity::beginner\tGit"
+	if lines[7] != want {
+		t.Errorf("got line\n%q\nwant\n%q", lines[7], want)
+	}
+}
+
+func TestExportAnkiPackage(t *testing.T) {
+	notes := ankiNotes(ankiSheet())
+	data, err := exportAnkiPackage(notes, time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, _ := file.Open()
+		files[file.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	if string(files["media"]) != "{}" {
+		t.Errorf("expected an empty media list, got %q", files["media"])
+	}
+
+	// Read the collection back the way Anki does, through SQLite
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := os.WriteFile(path, files["collection.anki2"], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var check string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&check); err != nil || check != "ok" {
+		t.Fatalf("expected an intact database, got %q, %v", check, err)
+	}
+	for _, name := range []string{"col", "notes", "cards", "revlog", "graves", "ix_cards_sched"} {
+		var n int
+		if db.QueryRow("SELECT count(*) FROM sqlite_master WHERE name = ?", name).Scan(&n); n != 1 {
+			t.Errorf("expected %s in the collection", name)
+		}
+	}
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM notes").Scan(&count); err != nil || count != len(notes) {
+		t.Fatalf("expected %d notes, got %d, %v", len(notes), count, err)
+	}
+	noteID := ankiID("note", notes[0].GUID)
+	var guid, tags, fields string
+	if err := db.QueryRow("SELECT guid, tags, flds FROM notes WHERE id = ?", noteID).Scan(&guid, &tags, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if guid != notes[0].GUID || tags != " basics day_one complexity::beginner " || fields != notes[0].Front+"\x1f"+notes[0].Back {
+		t.Errorf("got note %q %q %q", guid, tags, fields)
+	}
+	var deckID int64
+	if err := db.QueryRow("SELECT did FROM cards WHERE nid = ?", noteID).Scan(&deckID); err != nil || deckID != ankiID("deck", "Git") {
+		t.Errorf("expected a card in the Git deck, got deck %d, %v", deckID, err)
+	}
+	var decksJSON string
+	if err := db.QueryRow("SELECT decks FROM col WHERE id = 1").Scan(&decksJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decks map[string]struct{ Name string }
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		t.Fatal(err)
+	}
+	if len(decks) != 2 {
+		t.Errorf("expected the default deck and Git, got %v", decks)
+	}
+}
+
+func TestRunExportAnki(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "git.yaml")
+	os.WriteFile(path, []byte(`title: Git
+commands:
+  - name: git status
+    shortDesc: Show the working tree status
+    syntax: git status
+`), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if status := runExport([]string{"anki", path}, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected exit status 0, got %d (%s)", status, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "\tShow the working tree status\t<pre><code>git status</code></pre>\t\tGit\n") {
+		t.Errorf("expected the note as TSV, got:\n%s", stdout.String())
+	}
+
+	apkg := filepath.Join(dir, "git.apkg")
+	if status := runExport([]string{"anki", "-o", apkg, path}, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected exit status 0, got %d (%s)", status, stderr.String())
+	}
+	if data, _ := os.ReadFile(apkg); !bytes.HasPrefix(data, []byte("PK")) {
+		t.Error("expected a zip package")
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// Annotation is what a user adds to a command of a shared sheet for
+// themselves: it is kept apart from the sheet and shown only to them
+type Annotation struct {
+	Notes    []string  `yaml:"notes,omitempty"`
+	Examples []Example `yaml:"examples,omitempty"`
+	Tags     []string  `yaml:"tags,omitempty"` // private tags, filtered on like the sheet's own
+}
+
+// empty reports whether the annotation adds nothing
+func (a Annotation) empty() bool {
+	return len(a.Notes) == 0 && len(a.Examples) == 0 && len(a.Tags) == 0
+}
+
+// annotationStore holds every annotation, by sheet key and command name
+type annotationStore map[string]map[string]Annotation
+
+// dataDir returns the directory cheatcheat keeps the user's own data in,
+// following the XDG base directory spec
+func dataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "cheatcheat"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "cheatcheat"), nil
+}
+
+// annotationsPath returns the file annotations are kept in
+func annotationsPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "annotations.yaml"), nil
+}
+
+// loadAnnotations reads the annotations file. A missing file is not an error
+// and yields no annotations.
+func loadAnnotations() (annotationStore, error) {
+	store := make(annotationStore)
+	path, err := annotationsPath()
+	if err != nil {
+		return store, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, err
+	}
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return store, fmt.Errorf("%s: %w", path, err)
+	}
+	if store == nil {
+		store = make(annotationStore)
+	}
+	return store, nil
+}
+
+// saveAnnotations writes the annotations file
+func saveAnnotations(store annotationStore) error {
+	path, err := annotationsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(store)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// set stores a for the command of the sheet with the given key, or removes
+// the command's annotation when a is empty
+func (s annotationStore) set(sheetKey, command string, a Annotation) {
+	if a.empty() {
+		delete(s[sheetKey], command)
+		if len(s[sheetKey]) == 0 {
+			delete(s, sheetKey)
+		}
+		return
+	}
+	if s[sheetKey] == nil {
+		s[sheetKey] = make(map[string]Annotation)
+	}
+	s[sheetKey][command] = a
+}
+
+// annotationKey names a sheet in the annotations file: its path relative to
+// the cheatsheet root it is in, so annotations follow a shared sheet to
+// wherever it is checked out, or its absolute path when it is in no root.
+// Sheets after the first of a file get "#N" added.
+func annotationKey(sheet CheatSheet) string {
+	key, _ := filepath.Abs(sheet.Path)
+	for _, root := range sheetRoots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(abs, key); err == nil && !strings.HasPrefix(rel, "..") {
+			key = rel
+			break
+		}
+	}
+	key = filepath.ToSlash(key)
+	if sheet.Document > 0 {
+		key += fmt.Sprintf("#%d", sheet.Document+1)
+	}
+	return key
+}
+
+// annotateCommands returns a copy of commands with the annotations of the
+// sheet attached
+func annotateCommands(commands []Command, annotations map[string]Annotation) []Command {
+	annotated := make([]Command, len(commands))
+	for i, cmd := range commands {
+		cmd.Annotation = nil
+		if a, ok := annotations[cmd.Name]; ok {
+			cmd.Annotation = &a
+		}
+		annotated[i] = cmd
+	}
+	return annotated
+}
+
+// commandTags returns the tags of cmd, private ones included
+func commandTags(cmd Command) []string {
+	if cmd.Annotation == nil || len(cmd.Annotation.Tags) == 0 {
+		return cmd.Tags
+	}
+	tags := append([]string(nil), cmd.Tags...)
+	for _, tag := range cmd.Annotation.Tags {
+		if !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// annotationTemplate is offered in the editor for a command without an
+// annotation
+const annotationTemplate = `# notes:
+#   - "Only works on the VPN"
+# examples:
+#   - code: "git push origin HEAD:refs/for/main"
+#     description: "Push for review"
+# tags: ["daily"]
+`
+
+// annotationEditedMsg reports that the editor opened on an annotation has
+// exited
+type annotationEditedMsg struct {
+	file     string // temporary file holding the annotation being edited
+	sheetKey string // sheet the annotation belongs to
+	command  string // command the annotation belongs to
+	err      error  // set when the editor couldn't be run or failed
+}
+
+// annotateSelected opens the annotation of the selected command in $EDITOR,
+// by way of a temporary file
+func (m model) annotateSelected() tea.Cmd {
+	if len(m.commands) == 0 {
+		return nil
+	}
+	cmd := m.commands[m.currentCommand]
+	key := annotationKey(m.cheatSheet)
+	file, err := writeAnnotationFile(cmd, key)
+	if err != nil {
+		return func() tea.Msg { return errorMsg{err} }
+	}
+	return editAnnotation(file, key, cmd.Name, 0)
+}
+
+// writeAnnotationFile writes the annotation of cmd, or a commented template
+// when it has none, to a temporary file for editing
+func writeAnnotationFile(cmd Command, sheetKey string) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Your annotation of %q in %s, seen only by you.\n", cmd.Name, sheetKey)
+	b.WriteString("# Save an empty file to remove it.\n")
+	if cmd.Annotation != nil {
+		data, err := yaml.Marshal(cmd.Annotation)
+		if err != nil {
+			return "", err
+		}
+		b.Write(data)
+	} else {
+		b.WriteString(annotationTemplate)
+	}
+
+	file, err := os.CreateTemp("", "cheatcheat-annotation-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(b.Bytes())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return file.Name(), err
+}
+
+// editAnnotation opens the annotation file in $EDITOR at line
+func editAnnotation(file, sheetKey, command string, line int) tea.Cmd {
+	return tea.ExecProcess(editorCommand(file, line), func(err error) tea.Msg {
+		return annotationEditedMsg{file: file, sheetKey: sheetKey, command: command, err: err}
+	})
+}
+
+// saveEditedAnnotation stores the annotation the user wrote. When it doesn't
+// parse, the error is kept, like a broken sheet edit, so the user can edit
+// again; otherwise the detail view shows the new annotation.
+func (m *model) saveEditedAnnotation(msg annotationEditedMsg) {
+	m.annotationEdit = &msg
+	if msg.err != nil {
+		m.editErr = fmt.Errorf("running editor: %w", msg.err)
+		return
+	}
+	data, err := os.ReadFile(msg.file)
+	if err != nil {
+		m.editErr = err
+		return
+	}
+	var a Annotation
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&a); err != nil && !errors.Is(err, io.EOF) {
+		m.editErr = err
+		return
+	}
+
+	if m.annotations == nil {
+		m.annotations = make(annotationStore)
+	}
+	m.annotations.set(msg.sheetKey, msg.command, a)
+	if err := saveAnnotations(m.annotations); err != nil {
+		m.editErr = err
+		return
+	}
+	m.endAnnotationEdit()
+
+	m.cheatSheet.Commands = annotateCommands(m.cheatSheet.Commands, m.annotations[msg.sheetKey])
+	m.searchIndex = newSearchIndex(m.cheatSheet.Commands)
+	m.tagMenu = UniqueTags(taggedCommands(m.cheatSheet.Commands))
+	detail := m.showDetail
+	m.applyFilters()
+	for i, cmd := range m.commands {
+		if cmd.Name == msg.command {
+			m.selectCommand(i)
+			if detail {
+				m.openDetail()
+			}
+			break
+		}
+	}
+}
+
+// reEditAnnotation opens the annotation again after an edit left it broken,
+// at the line the error points to when there is one
+func (m model) reEditAnnotation() tea.Cmd {
+	a := m.annotationEdit
+	return editAnnotation(a.file, a.sheetKey, a.command, errorLine(m.editErr))
+}
+
+// endAnnotationEdit forgets the annotation being edited and its file
+func (m *model) endAnnotationEdit() {
+	if m.annotationEdit != nil {
+		os.Remove(m.annotationEdit.file)
+	}
+	m.annotationEdit = nil
+	m.editErr = nil
+}
+
+// taggedCommands returns commands with their private tags among their tags,
+// for the tag menu
+func taggedCommands(commands []Command) []Command {
+	tagged := make([]Command, len(commands))
+	for i, cmd := range commands {
+		cmd.Tags = commandTags(cmd)
+		tagged[i] = cmd
+	}
+	return tagged
+}
+
+// orphanedAnnotations lists the annotations whose sheet or command can no
+// longer be found, as "sheet: command: reason" lines
+func orphanedAnnotations(store annotationStore) []string {
+	var keys []string
+	for key := range store {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var orphans []string
+	for _, key := range keys {
+		var commands []string
+		for name := range store[key] {
+			commands = append(commands, name)
+		}
+		sort.Strings(commands)
+
+		sheet, err := findAnnotatedSheet(key)
+		for _, name := range commands {
+			// Annotations are shown by exact name, as annotateCommands
+			// matches them, so one kept under an alias is orphaned too
+			cmd, found := findCommand(sheet.Commands, name)
+			switch {
+			case err != nil:
+				orphans = append(orphans, fmt.Sprintf("%s: %s: %v", key, name, err))
+			case !found:
+				orphans = append(orphans, fmt.Sprintf("%s: %s: no such command, renamed or removed", key, name))
+			case cmd.Name != name:
+				orphans = append(orphans, fmt.Sprintf("%s: %s: an alias of %q, annotations go by command name", key, name, cmd.Name))
+			}
+		}
+	}
+	return orphans
+}
+
+// findAnnotatedSheet loads the sheet an annotation key names
+func findAnnotatedSheet(key string) (CheatSheet, error) {
+	path, doc := key, 0
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		if n, err := strconv.Atoi(key[i+1:]); err == nil && n > 0 {
+			path, doc = key[:i], n-1
+		}
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		found := false
+		for _, root := range sheetRoots {
+			if _, err := os.Stat(filepath.Join(root, path)); err == nil {
+				path, found = filepath.Join(root, path), true
+				break
+			}
+		}
+		if !found {
+			return CheatSheet{}, errors.New("sheet not found in any cheatsheet directory")
+		}
+	}
+	return loadCheatSheetAt(path, doc)
+}
+
+// runAnnotations implements "cheatcheat annotations": it lists the annotated
+// commands of every sheet, and with --orphans only the annotations whose
+// sheet or command is gone. It exits non-zero when there are orphans.
+func runAnnotations(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("annotations", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	orphansOnly := flags.Bool("orphans", false, "list only annotations whose sheet or command is gone")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat annotations [--orphans]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	store, err := loadAnnotations()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if !*orphansOnly {
+		var lines []string
+		for key, commands := range store {
+			for name, a := range commands {
+				lines = append(lines, fmt.Sprintf("%s: %s: %s", key, name, a.summary()))
+			}
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			fmt.Fprintln(stdout, line)
+		}
+	}
+
+	orphans := orphanedAnnotations(store)
+	for _, orphan := range orphans {
+		fmt.Fprintf(stdout, "orphaned: %s\n", orphan)
+	}
+	if len(orphans) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// summary counts what the annotation adds, as in "2 notes, 1 tag"
+func (a Annotation) summary() string {
+	var parts []string
+	for _, part := range []struct {
+		n    int
+		what string
+	}{{len(a.Notes), "note"}, {len(a.Examples), "example"}, {len(a.Tags), "tag"}} {
+		if part.n > 0 {
+			parts = append(parts, plural(part.n, part.what))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
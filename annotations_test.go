@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// annotate finishes an annotation edit of the selected command as if the
+// user had written text in the editor
+func annotate(t *testing.T, m model, text string) model {
+	t.Helper()
+	cmd, key := m.commands[m.currentCommand], annotationKey(m.cheatSheet)
+	file, err := writeAnnotationFile(cmd, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(file) })
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), cmd.Name) {
+		t.Errorf("expected the command named in the file, got:\n%s", data)
+	}
+	if err := os.WriteFile(file, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := m.Update(annotationEditedMsg{file: file, sheetKey: key, command: cmd.Name})
+	return tm.(model)
+}
+
+func TestAnnotationKey(t *testing.T) {
+	shared, personal := t.TempDir(), t.TempDir()
+	withRoots(t, personal, shared)
+	if got := annotationKey(CheatSheet{Path: filepath.Join(shared, "dev", "git.yaml")}); got != "dev/git.yaml" {
+		t.Errorf("expected the path within the root, got %q", got)
+	}
+	if got := annotationKey(CheatSheet{Path: filepath.Join(shared, "k8s.yaml"), Document: 1}); got != "k8s.yaml#2" {
+		t.Errorf("expected the document numbered, got %q", got)
+	}
+	outside := filepath.Join(t.TempDir(), "other.yaml")
+	if got := annotationKey(CheatSheet{Path: outside}); got != filepath.ToSlash(outside) {
+		t.Errorf("expected the absolute path outside the roots, got %q", got)
+	}
+}
+
+func TestAnnotateCommand(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m, path := editedModel(t, "first")
+	m.openDetail()
+
+	m = annotate(t, m, `notes: ["Only on the VPN"]
+examples:
+  - code: first --quiet
+    description: our way
+tags: [mine]
+`)
+	if m.editErr != nil {
+		t.Fatalf("expected the annotation saved, got %v", m.editErr)
+	}
+	detail := ansi.Strip(m.viewport.View())
+	for _, want := range []string{"Your Notes:", "Tags: mine", "$ first --quiet", "• Only on the VPN"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected %q in the detail view:\n%s", want, detail)
+		}
+	}
+
+	// The sheet is left alone and the annotation is kept for the next session
+	if data, _ := os.ReadFile(path); string(data) != editFixture {
+		t.Errorf("expected the sheet unchanged, got:\n%s", data)
+	}
+	store, err := loadAnnotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store[annotationKey(m.cheatSheet)]["first"].Notes; !reflect.DeepEqual(got, []string{"Only on the VPN"}) {
+		t.Errorf("expected the note stored, got %v", got)
+	}
+	reloaded := initialModel(path, filepath.Dir(path))
+	reloaded.annotations = store
+	tm, _ := reloaded.Update(loadCheatSheetMsg(path, 0))
+	reloaded = tm.(model)
+	if !containsString(reloaded.tagMenu, "mine") {
+		t.Errorf("expected the private tag in the tag menu, got %v", reloaded.tagMenu)
+	}
+	reloaded.setTagFilter(tagFilter{include: []string{"mine"}})
+	if got := commandNames(reloaded.commands); !reflect.DeepEqual(got, []string{"first"}) {
+		t.Errorf("expected the private tag to filter, got %v", got)
+	}
+
+	// Saving nothing removes the annotation
+	m = annotate(t, m, "# nothing left\n")
+	if m.commands[m.currentCommand].Annotation != nil || len(m.annotations) != 0 {
+		t.Errorf("expected the annotation removed, got %+v", m.annotations)
+	}
+}
+
+func TestAnnotationParseError(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m, _ := editedModel(t, "second")
+	m = annotate(t, m, "notes: [unclosed\n")
+	if m.editErr == nil || m.annotationEdit == nil {
+		t.Fatal("expected the parse error kept for editing again")
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "The edited annotation has errors") {
+		t.Errorf("expected the annotation error shown, got:\n%s", view)
+	}
+	file := m.annotationEdit.file
+
+	m = press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.editErr != nil || m.annotationEdit != nil {
+		t.Error("expected Esc to discard the broken annotation")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file removed, got %v", err)
+	}
+
+	m = annotate(t, m, "nots: [typo]\n")
+	if m.editErr == nil {
+		t.Error("expected unknown fields rejected")
+	}
+}
+
+func TestRunAnnotations(t *testing.T) {
+	root := t.TempDir()
+	withRoots(t, root)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	os.WriteFile(filepath.Join(root, "git.yaml"), []byte(`title: Git
+commands:
+  - name: git status
+    shortDesc: show status
+    syntax: git status
+    aliases: [git st]
+`), 0o644)
+	saveAnnotations(annotationStore{
+		"git.yaml":  {"git status": {Notes: []string{"a", "b"}, Tags: []string{"daily"}}, "git whatchanged": {Notes: []string{"gone"}}, "git st": {Notes: []string{"by alias"}}},
+		"gone.yaml": {"ls": {Tags: []string{"x"}}},
+	})
+
+	var stdout, stderr bytes.Buffer
+	if status := runAnnotations(nil, &stdout, &stderr); status != 1 {
+		t.Errorf("expected exit status 1 with orphans, got %d (%s)", status, stderr.String())
+	}
+	want := `git.yaml: git st: 1 note
+git.yaml: git status: 2 notes, 1 tag
+git.yaml: git whatchanged: 1 note
+gone.yaml: ls: 1 tag
+orphaned: git.yaml: git st: an alias of "git status", annotations go by command name
+orphaned: git.yaml: git whatchanged: no such command, renamed or removed
+orphaned: gone.yaml: ls: sheet not found in any cheatsheet directory
+`
+	if got := stdout.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// What is reported as orphaned is what the list doesn't show
+	cmds := annotateCommands([]Command{{Name: "git status", Aliases: []string{"git st"}}}, map[string]Annotation{"git st": {Notes: []string{"by alias"}}})
+	if cmds[0].Annotation != nil {
+		t.Errorf("expected annotations matched by exact name only, got %+v", cmds[0].Annotation)
+	}
+
+	stdout.Reset()
+	runAnnotations([]string{"--orphans"}, &stdout, &stderr)
+	if strings.Contains(stdout.String(), "2 notes") {
+		t.Errorf("expected only orphans listed, got:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shellBuiltins are words that start a command line but aren't programs to
+// look up on $PATH
+var shellBuiltins = []string{
+	".", "alias", "bg", "cd", "echo", "eval", "exec", "exit", "export", "fg", "for",
+	"if", "jobs", "printf", "pwd", "read", "set", "source", "test", "type", "ulimit",
+	"umask", "unalias", "unset", "until", "wait", "while",
+}
+
+// lookPath finds a program on $PATH; a variable so tests can stand in for it
+var lookPath = exec.LookPath
+
+// lineBinary returns the program the command line text runs: the first word
+// of its first line that isn't a comment, after sudo, a "$ " prompt and
+// variable assignments. It is "" for builtins and placeholders like <cmd>.
+func lineBinary(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for len(fields) > 1 && (fields[0] == "$" || fields[0] == "sudo" || strings.Contains(fields[0], "=")) {
+			fields = fields[1:]
+		}
+		word := fields[0]
+		if strings.ContainsAny(word, "<>[]{}()$|&;='\"`") || containsString(shellBuiltins, word) {
+			return ""
+		}
+		return word
+	}
+	return ""
+}
+
+// commandBinaries lists the programs the syntax and examples of cmd run
+func commandBinaries(cmd Command) []string {
+	var binaries []string
+	texts := []string{cmd.Syntax}
+	for _, ex := range cmd.Examples {
+		texts = append(texts, ex.Code)
+	}
+	for _, text := range texts {
+		if binary := lineBinary(text); binary != "" && !containsString(binaries, binary) {
+			binaries = append(binaries, binary)
+		}
+	}
+	return binaries
+}
+
+// findBinaries looks up the programs run by commands that aren't in known,
+// reporting for each whether it is on $PATH
+func findBinaries(commands []Command, known map[string]bool) map[string]bool {
+	found := make(map[string]bool)
+	for _, cmd := range commands {
+		for _, binary := range commandBinaries(cmd) {
+			if _, ok := known[binary]; ok {
+				continue
+			}
+			if _, ok := found[binary]; !ok {
+				_, err := lookPath(binary)
+				found[binary] = err == nil
+			}
+		}
+	}
+	return found
+}
+
+// markMissing returns a copy of commands with Missing set to the programs
+// each runs that known reports missing. Programs not yet looked up count as
+// installed.
+func markMissing(commands []Command, known map[string]bool) []Command {
+	marked := make([]Command, len(commands))
+	for i, cmd := range commands {
+		cmd.Missing = nil
+		for _, binary := range commandBinaries(cmd) {
+			if found, ok := known[binary]; ok && !found {
+				cmd.Missing = append(cmd.Missing, binary)
+			}
+		}
+		marked[i] = cmd
+	}
+	return marked
+}
+
+// binariesMsg reports which programs were found on $PATH
+type binariesMsg map[string]bool
+
+// checkBinaries returns a command that looks up the programs the commands
+// run in the background, skipping those in known
+func checkBinaries(commands []Command, known map[string]bool) tea.Cmd {
+	return func() tea.Msg {
+		found := findBinaries(commands, known)
+		if len(found) == 0 {
+			return nil
+		}
+		return binariesMsg(found)
+	}
+}
+
+// runDoctor implements "cheatcheat doctor": it lists, sheet by sheet, the
+// programs used by the commands that aren't on $PATH, with the number of
+// commands needing each. It exits non-zero when any program is missing.
+func runDoctor(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat doctor [paths...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	targets := flags.Args()
+	if len(targets) == 0 {
+		for _, root := range sheetRoots {
+			if _, err := os.Stat(root); err == nil {
+				targets = append(targets, root)
+			}
+		}
+	}
+	paths, err := sheetPaths(targets)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	status := 0
+	known := make(map[string]bool)
+	for _, path := range paths {
+		sheets, err := LoadCheatSheets(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 1
+			continue
+		}
+		for i, sheet := range sheets {
+			for binary, found := range findBinaries(sheet.Commands, known) {
+				known[binary] = found
+			}
+			needed := make(map[string]int)
+			for _, cmd := range markMissing(sheet.Commands, known) {
+				for _, binary := range cmd.Missing {
+					needed[binary]++
+				}
+			}
+			if len(needed) == 0 {
+				continue
+			}
+
+			var missing []string
+			for binary, n := range needed {
+				missing = append(missing, fmt.Sprintf("%s (%s)", binary, plural(n, "command")))
+			}
+			sort.Strings(missing)
+			name := path
+			if len(sheets) > 1 {
+				name = fmt.Sprintf("%s: document %d", path, i+1)
+			}
+			fmt.Fprintf(stdout, "%s: missing %s\n", name, strings.Join(missing, ", "))
+			status = 1
+		}
+	}
+	return status
+}
+
+// plural formats a count of things, as in "1 command" or "3 commands"
+func plural(n int, thing string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, thing)
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
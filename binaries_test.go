@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// withPath makes lookPath find only the given programs
+func withPath(t *testing.T, installed ...string) {
+	t.Helper()
+	saved := lookPath
+	t.Cleanup(func() { lookPath = saved })
+	lookPath = func(name string) (string, error) {
+		if containsString(installed, name) {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestLineBinary(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"git commit -m <message>", "git"},
+		{"# stage first\ngit add .", "git"},
+		{"sudo systemctl restart nginx", "systemctl"},
+		{"$ docker ps", "docker"},
+		{"KUBECONFIG=~/.kube/prod kubectl get pods", "kubectl"},
+		{"cd /tmp && ls", ""},
+		{"<command> --help", ""},
+		{"{{ .tool }} run", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := lineBinary(tt.text); got != tt.want {
+			t.Errorf("lineBinary(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	cmd := Command{Syntax: "kubectl logs <pod>", Examples: []Example{{Code: "kubectl logs web"}, {Code: "stern web"}}}
+	if got := commandBinaries(cmd); !reflect.DeepEqual(got, []string{"kubectl", "stern"}) {
+		t.Errorf("commandBinaries() = %v, want [kubectl stern]", got)
+	}
+}
+
+func TestMissingBinaries(t *testing.T) {
+	withPath(t, "git")
+	sheet := CheatSheet{Title: "Tools", Commands: []Command{
+		{Name: "git status", ShortDesc: "show status", Syntax: "git status"},
+		{Name: "kubectl get", ShortDesc: "list resources", Syntax: "kubectl get <kind>"},
+		{Name: "tail logs", ShortDesc: "follow logs", Syntax: "kubectl logs -f <pod>", Examples: []Example{{Code: "stern web"}}},
+	}}
+
+	var tm tea.Model = initialModel("", "cheatsheets")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, cmd := tm.Update(cheatSheetLoadedMsg(sheet))
+	if cmd == nil {
+		t.Fatal("expected the programs to be looked up after loading")
+	}
+	m := press(tm.(model), tea.KeyMsg{Type: tea.KeyDown})
+	tm, _ = m.Update(cmd())
+	m = tm.(model)
+
+	if m.currentCommand != 1 {
+		t.Errorf("expected the selection kept on command 2, got %d", m.currentCommand+1)
+	}
+	list := ansi.Strip(RenderCommandList("", m.commands, m.currentCommand))
+	for _, want := range []string{"✗ not installed: kubectl\n", "✗ not installed: kubectl, stern\n"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("expected %q in the list:\n%s", want, list)
+		}
+	}
+	if strings.Count(list, "not installed") != 2 {
+		t.Errorf("expected git status not marked, got:\n%s", list)
+	}
+
+	m = press(m, runes("i"))
+	if got := commandNames(m.commands); !reflect.DeepEqual(got, []string{"git status"}) {
+		t.Errorf("expected only installed commands, got %v", got)
+	}
+
+	// Reloading the sheet marks it from what was found before
+	tm, cmd = m.Update(cheatSheetLoadedMsg(sheet))
+	m = tm.(model)
+	if msg := cmd(); msg != nil {
+		t.Errorf("expected nothing left to look up, got %v", msg)
+	}
+	if got := m.cheatSheet.Commands[1].Missing; !reflect.DeepEqual(got, []string{"kubectl"}) {
+		t.Errorf("expected kubectl marked missing again, got %v", got)
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	withPath(t, "git")
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "git.yaml"), []byte(`title: Git
+commands:
+  - name: git status
+    shortDesc: show status
+    syntax: git status
+`), 0o644)
+	os.WriteFile(filepath.Join(dir, "k8s.yaml"), []byte(`title: Kubernetes
+commands:
+  - name: kubectl get
+    shortDesc: list resources
+    syntax: kubectl get <kind>
+  - name: kubectl logs
+    shortDesc: show logs
+    syntax: kubectl logs <pod>
+    examples:
+      - code: stern web
+`), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if status := runDoctor([]string{dir}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected exit status 1, got %d (%s)", status, stderr.String())
+	}
+	want := filepath.Join(dir, "k8s.yaml") + ": missing kubectl (2 commands), stern (1 command)\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	stdout.Reset()
+	if status := runDoctor([]string{filepath.Join(dir, "git.yaml")}, &stdout, &stderr); status != 0 || stdout.Len() != 0 {
+		t.Errorf("expected nothing missing for git.yaml, got %d: %q", status, stdout.String())
+	}
+}
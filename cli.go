@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+)
+
+// subcommand is a non-interactive mode of cheatcheat, along with the flags
+// and arguments it takes, which shell completion offers
+type subcommand struct {
+	run   func(args []string, stdout, stderr io.Writer) int
+	flags []commandFlag
+	args  argKind
+}
+
+// commandFlag is a flag of a subcommand
+type commandFlag struct {
+	name   string
+	value  bool            // whether the flag takes a value
+	values func() []string // the values completed for it; nil completes file names
+}
+
+// argKind is what the positional arguments of a subcommand are
+type argKind int
+
+const (
+	argsNone   argKind = iota // no arguments, or ones completion can't guess
+	argsSheets                // sheets, or directories of them
+	argsShell                 // the shell to print a completion script for
+	argsTarget                // an export target, followed by its own flags and arguments
+)
+
+// subcommands are the non-interactive modes of cheatcheat, run as
+// "cheatcheat <name> args...". Each returns the process exit status.
+var subcommands = map[string]subcommand{
+	"fmt": {
+		run:   runFmt,
+		flags: []commandFlag{{name: "w"}, {name: "check"}},
+		args:  argsSheets,
+	},
+	"convert": {
+		run: runConvert,
+		flags: []commandFlag{
+			{name: "to", value: true, values: func() []string {
+				var names []string
+				for _, f := range sheetFormats {
+					names = append(names, f.name)
+				}
+				return names
+			}},
+			{name: "o", value: true},
+		},
+		args: argsSheets,
+	},
+	"lint": {
+		run:   runLint,
+		flags: []commandFlag{{name: "var", value: true}},
+		args:  argsSheets,
+	},
+	"doctor": {run: runDoctor, args: argsSheets},
+	"export": {run: runExport, args: argsTarget},
+	"verify": {
+		run: runVerify,
+		flags: []commandFlag{
+			{name: "format", value: true, values: func() []string { return []string{"text", "junit"} }},
+			{name: "timeout", value: true},
+		},
+		args: argsSheets,
+	},
+	"annotations": {
+		run:   runAnnotations,
+		flags: []commandFlag{{name: "orphans"}},
+	},
+	"quiz": {
+		run: runQuiz,
+		flags: []commandFlag{
+			{name: "reverse"},
+			{name: "tag", value: true},
+			{name: "complexity", value: true, values: func() []string { return complexityNames[1:] }},
+			{name: "limit", value: true},
+		},
+		args: argsSheets,
+	},
+	"completion": {run: runCompletion, args: argsShell},
+}
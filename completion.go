@@ -0,0 +1,274 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completeCommand is the hidden subcommand the completion scripts call with
+// the words typed after "cheatcheat", the one being completed last. It prints
+// the candidates for that word a line each; when there are none, the scripts
+// complete file names instead.
+const completeCommand = "__complete"
+
+// completionScripts are the scripts "cheatcheat completion <shell>" prints
+var completionScripts = map[string]string{
+	"bash": `# bash completion for cheatcheat. Load it with
+#   source <(cheatcheat completion bash)
+_cheatcheat() {
+    local word words candidates
+    # The words as they read unquoted
+    words=("${COMP_WORDS[@]:1:COMP_CWORD}")
+    words=("${words[@]//\\/}")
+    words=("${words[@]#[\"\']}")
+    mapfile -t candidates < <(cheatcheat __complete "${words[@]}" 2>/dev/null)
+    COMPREPLY=()
+    for word in "${candidates[@]}"; do
+        COMPREPLY+=("$(printf '%q' "$word")")
+    done
+}
+complete -o default -F _cheatcheat cheatcheat
+`,
+	"zsh": `#compdef cheatcheat
+# zsh completion for cheatcheat. Load it with
+#   source <(cheatcheat completion zsh)
+# or save it as _cheatcheat in a directory on $fpath.
+_cheatcheat() {
+    local -a candidates
+    candidates=("${(@f)$(cheatcheat __complete "${(@Q)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+if [[ $funcstack[1] == _cheatcheat ]]; then
+    _cheatcheat "$@"
+else
+    compdef _cheatcheat cheatcheat
+fi
+`,
+	"fish": `# fish completion for cheatcheat. Load it with
+#   cheatcheat completion fish | source
+# or save it as cheatcheat.fish in ~/.config/fish/completions.
+function __cheatcheat_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    set -l current (commandline -ct)
+    set -l candidates (cheatcheat __complete $words "$current" 2>/dev/null)
+    if set -q candidates[1]
+        printf '%s\n' $candidates
+    else
+        __fish_complete_path "$current"
+    end
+end
+complete -c cheatcheat -f -a '(__cheatcheat_complete)'
+`,
+}
+
+// globalFlags are the flags cheatcheat takes before a subcommand or sheet,
+// all of which take a value
+var globalFlags = []string{"dir", "var"}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// matching returns the candidates starting with prefix
+func matching(prefix string, candidates []string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// splitFlag splits a word like "--name=value" into the flag's name and
+// value, hasValue telling whether it has one
+func splitFlag(word string) (name, value string, hasValue bool) {
+	word = strings.TrimPrefix(strings.TrimPrefix(word, "-"), "-")
+	return strings.Cut(word, "=")
+}
+
+// flagName spells a flag the way the usage messages do: one dash for single
+// letters and two for words
+func flagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// sheetCompletions returns the sheets found in roots, as paths, leaving out
+// those hidden by a sheet at the same relative path in an earlier root
+func sheetCompletions(roots []string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		names, err := DiscoverCheatsheets(root)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				paths = append(paths, filepath.Join(root, name))
+			}
+		}
+	}
+	return paths
+}
+
+// sheetCommandNames returns the names of the commands of the sheet at path,
+// or none when it can't be loaded
+func sheetCommandNames(path string) []string {
+	sheet, err := loadCheatSheetAt(path, 0)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, cmd := range sheet.Commands {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+// completeWords returns the candidates for the last of words, the arguments
+// typed after "cheatcheat": flags, subcommands and their arguments, the
+// sheets in the cheatsheet roots, or --dir's when it is given, and the
+// commands of the sheet opened in direct mode
+func completeWords(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, args := words[len(words)-1], words[:len(words)-1]
+
+	roots := sheetRoots
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := splitFlag(args[0])
+		args = args[1:]
+		if !hasValue && containsString(globalFlags, name) {
+			if len(args) == 0 {
+				return nil
+			}
+			value, args = args[0], args[1:]
+		}
+		if name == "dir" {
+			roots = splitRoots(value)
+		}
+	}
+
+	if len(args) == 0 {
+		if strings.HasPrefix(current, "-") {
+			var flags []string
+			for _, name := range globalFlags {
+				flags = append(flags, flagName(name))
+			}
+			return matching(current, flags)
+		}
+		return matching(current, append(sortedKeys(subcommands), sheetCompletions(roots)...))
+	}
+
+	if cmd, ok := subcommands[args[0]]; ok {
+		return completeArgs(cmd, args[1:], current, roots)
+	}
+	if len(args) == 1 {
+		return matching(current, sheetCommandNames(args[0]))
+	}
+	return nil
+}
+
+// completeArgs returns the candidates for current among the arguments of
+// cmd after args, going by the flags and kind of arguments it declares: its
+// flags, their values when it knows them, and its positional arguments
+func completeArgs(cmd subcommand, args []string, current string, roots []string) []string {
+	if cmd.args == argsTarget {
+		if len(args) == 0 {
+			return matching(current, sortedKeys(exporters))
+		}
+		if target, ok := exporters[args[0]]; ok {
+			return completeArgs(target, args[1:], current, roots)
+		}
+		return nil
+	}
+
+	flags := make(map[string]commandFlag)
+	for _, f := range cmd.flags {
+		flags[f.name] = f
+	}
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			positional = append(positional, args[i])
+			continue
+		}
+		name, _, hasValue := splitFlag(args[i])
+		if f := flags[name]; f.value && !hasValue {
+			if i == len(args)-1 {
+				// current is the flag's value
+				if f.values != nil {
+					return matching(current, f.values())
+				}
+				return nil
+			}
+			i++
+		}
+	}
+
+	if strings.HasPrefix(current, "-") {
+		var names []string
+		for _, name := range sortedKeys(flags) {
+			names = append(names, flagName(name))
+		}
+		return matching(current, names)
+	}
+	switch cmd.args {
+	case argsSheets:
+		return matching(current, sheetCompletions(roots))
+	case argsShell:
+		if len(positional) == 0 {
+			return matching(current, sortedKeys(completionScripts))
+		}
+	}
+	return nil
+}
+
+// runCompletion implements "cheatcheat completion": the completion script
+// for a shell, completing subcommands, flags, sheets and commands
+func runCompletion(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("completion", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: cheatcheat completion %s\n", strings.Join(sortedKeys(completionScripts), "|"))
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	script, ok := completionScripts[flags.Arg(0)]
+	if flags.NArg() != 1 || !ok {
+		flags.Usage()
+		return 2
+	}
+	fmt.Fprint(stdout, script)
+	return 0
+}
+
+// runComplete implements the hidden completeCommand
+func runComplete(args []string, stdout, stderr io.Writer) int {
+	for _, candidate := range completeWords(args) {
+		fmt.Fprintln(stdout, candidate)
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCompleteWords(t *testing.T) {
+	team, mine := t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(team, "linux"), 0o755)
+	os.WriteFile(filepath.Join(team, "git.yaml"), []byte(`title: Git
+commands:
+  - name: git status
+  - name: git switch
+  - name: git log
+`), 0o644)
+	os.WriteFile(filepath.Join(team, "linux", "tar.yaml"), []byte("title: tar\ncommands: []\n"), 0o644)
+	os.WriteFile(filepath.Join(mine, "git.yaml"), []byte("title: My Git\ncommands: []\n"), 0o644)
+	withRoots(t, mine, team)
+	git, tar := filepath.Join(mine, "git.yaml"), filepath.Join(team, "linux", "tar.yaml")
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"co"}, []string{"completion", "convert"}},
+		{[]string{"-"}, []string{"--dir", "--var"}},
+		{[]string{"--dir", ""}, nil},
+		{[]string{"--dir", team, "lint", filepath.Join(team, "g")}, []string{filepath.Join(team, "git.yaml")}},
+		{[]string{"--var=os=linux", "lint", mine}, []string{git}},
+		{[]string{"lint", ""}, []string{git, tar}},
+		{[]string{"fmt", "-"}, []string{"--check", "-w"}},
+		{[]string{"verify", "--format", ""}, []string{"text", "junit"}},
+		{[]string{"verify", "--timeout", ""}, nil},
+		{[]string{"verify", "--timeout", "5s", "--format=junit", mine}, []string{git}},
+		{[]string{"quiz", "--complexity", "a"}, []string{"advanced"}},
+		{[]string{"annotations", ""}, nil},
+		{[]string{"completion", ""}, []string{"bash", "fish", "zsh"}},
+		{[]string{"completion", "bash", ""}, nil},
+		{[]string{"export", ""}, []string{"anki", "html", "man"}},
+		{[]string{"export", "man", "--"}, []string{"--out"}},
+		{[]string{"export", "man", "--out", ""}, nil},
+		{[]string{filepath.Join(team, "git.yaml"), "git s"}, []string{"git status", "git switch"}},
+		{[]string{filepath.Join(team, "git.yaml"), "git status", ""}, nil},
+	}
+	for _, tt := range tests {
+		if got := completeWords(tt.words); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("completeWords(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+
+	// Sheets come after the subcommands, and the hidden hook isn't offered
+	all := completeWords([]string{""})
+	if !containsString(all, "quiz") || all[len(all)-1] != tar || containsString(all, completeCommand) {
+		t.Errorf("got %q", all)
+	}
+}
+
+// TestSubcommandFlags checks that the flags the registries declare for
+// completion are the ones the subcommands parse, as their -h output lists them
+func TestSubcommandFlags(t *testing.T) {
+	flagLine := regexp.MustCompile(`^  -(\S+)( \S+)?(\t|$)`)
+	check := func(name string, cmd subcommand) {
+		var help bytes.Buffer
+		cmd.run([]string{"-h"}, io.Discard, &help)
+		parsed := make(map[string]bool)
+		scanner := bufio.NewScanner(&help)
+		for scanner.Scan() {
+			if m := flagLine.FindStringSubmatch(scanner.Text()); m != nil {
+				parsed[m[1]] = m[2] != ""
+			}
+		}
+		declared := make(map[string]bool)
+		for _, f := range cmd.flags {
+			declared[f.name] = f.value
+		}
+		if !reflect.DeepEqual(declared, parsed) {
+			t.Errorf("%s: declares flags %v, parses %v", name, declared, parsed)
+		}
+	}
+	for name, cmd := range subcommands {
+		check(name, cmd)
+	}
+	for name, cmd := range exporters {
+		check("export "+name, cmd)
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var stdout, stderr bytes.Buffer
+		if status := runCompletion([]string{shell}, &stdout, &stderr); status != 0 {
+			t.Fatalf("%s: expected exit status 0, got %d (%s)", shell, status, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "cheatcheat "+completeCommand) {
+			t.Errorf("%s: expected the script to call %s, got:\n%s", shell, completeCommand, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := runCompletion([]string{"tcsh"}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected exit status 2 for an unknown shell, got %d", status)
+	}
+	if !strings.Contains(stderr.String(), "usage: cheatcheat completion bash|fish|zsh") {
+		t.Errorf("got %q", stderr.String())
+	}
+}
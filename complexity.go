@@ -0,0 +1,103 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// complexityLevel orders the complexity values a command may declare. The
+// zero value means the command doesn't declare one.
+type complexityLevel int
+
+const (
+	complexityUnset complexityLevel = iota
+	complexityBeginner
+	complexityIntermediate
+	complexityAdvanced
+)
+
+var complexityNames = []string{"", "beginner", "intermediate", "advanced"}
+
+// parseComplexity maps a complexity value to its level, ignoring case and
+// surrounding space. ok is false for values that aren't a known level.
+func parseComplexity(s string) (level complexityLevel, ok bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range complexityNames {
+		if s == name {
+			return complexityLevel(i), true
+		}
+	}
+	return complexityUnset, false
+}
+
+func (c complexityLevel) String() string {
+	if c < complexityUnset || int(c) >= len(complexityNames) {
+		return ""
+	}
+	return complexityNames[c]
+}
+
+// complexityFilter keeps commands at a given level: every level up to and
+// including it (cumulative), or that level only (exact). The zero value lets
+// every command through.
+type complexityFilter struct {
+	level complexityLevel
+	exact bool
+}
+
+// Matches reports whether a command of the given complexity passes the filter.
+// Commands without a known complexity only pass when no level is selected.
+func (f complexityFilter) Matches(complexity string) bool {
+	if f.level == complexityUnset {
+		return true
+	}
+	level, _ := parseComplexity(complexity)
+	if level == complexityUnset {
+		return false
+	}
+	if f.exact {
+		return level == f.level
+	}
+	return level <= f.level
+}
+
+// Next cycles the selected level: off, beginner, intermediate, advanced, off
+func (f complexityFilter) Next() complexityFilter {
+	f.level = (f.level + 1) % complexityLevel(len(complexityNames))
+	return f
+}
+
+// ToggleExact switches between cumulative and exact matching
+func (f complexityFilter) ToggleExact() complexityFilter {
+	f.exact = !f.exact
+	return f
+}
+
+func (f complexityFilter) String() string {
+	if f.level == complexityUnset {
+		return "any"
+	}
+	if f.exact {
+		return "= " + f.level.String()
+	}
+	return "≤ " + f.level.String()
+}
+
+// sortByComplexity returns the commands ordered from beginner to advanced,
+// keeping the sheet order within a level and putting commands without a
+// complexity last. The input slice is left untouched.
+func sortByComplexity(commands []Command) []Command {
+	sorted := make([]Command, len(commands))
+	copy(sorted, commands)
+	rank := func(cmd Command) complexityLevel {
+		level, _ := parseComplexity(cmd.Complexity)
+		if level == complexityUnset {
+			return complexityAdvanced + 1
+		}
+		return level
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
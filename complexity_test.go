@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComplexityFilter(t *testing.T) {
+	tests := []struct {
+		filter     complexityFilter
+		complexity string
+		want       bool
+	}{
+		{complexityFilter{}, "", true},
+		{complexityFilter{}, "advanced", true},
+		{complexityFilter{level: complexityIntermediate}, "beginner", true},
+		{complexityFilter{level: complexityIntermediate}, "Intermediate", true},
+		{complexityFilter{level: complexityIntermediate}, "advanced", false},
+		{complexityFilter{level: complexityIntermediate}, "", false},
+		{complexityFilter{level: complexityIntermediate, exact: true}, "beginner", false},
+		{complexityFilter{level: complexityIntermediate, exact: true}, "intermediate", true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(tt.complexity); got != tt.want {
+			t.Errorf("%s: Matches(%q) = %v, want %v", tt.filter, tt.complexity, got, tt.want)
+		}
+	}
+
+	// Cycling runs through every level and back to off
+	f := complexityFilter{}
+	for _, want := range []complexityLevel{complexityBeginner, complexityIntermediate, complexityAdvanced, complexityUnset} {
+		f = f.Next()
+		if f.level != want {
+			t.Errorf("Expected level %v, got %v", want, f.level)
+		}
+	}
+}
+
+func TestSortByComplexity(t *testing.T) {
+	commands := []Command{
+		{Name: "a", Complexity: "advanced"},
+		{Name: "b"},
+		{Name: "c", Complexity: "beginner"},
+		{Name: "d", Complexity: "intermediate"},
+		{Name: "e", Complexity: "beginner"},
+	}
+
+	var names []string
+	for _, cmd := range sortByComplexity(commands) {
+		names = append(names, cmd.Name)
+	}
+	if got := strings.Join(names, ""); got != "cedab" {
+		t.Errorf("Expected order cedab, got %s", got)
+	}
+	if commands[0].Name != "a" {
+		t.Errorf("Expected the input slice to be left untouched")
+	}
+}
+
+func TestLoadCheatSheetComplexity(t *testing.T) {
+	dir := t.TempDir()
+
+	good := filepath.Join(dir, "good.yaml")
+	os.WriteFile(good, []byte(`title: Good
+commands:
+  - name: one
+    complexity: Beginner
+`), 0644)
+	sheet, err := LoadCheatSheet(good)
+	if err != nil {
+		t.Fatalf("Failed to load cheatsheet: %v", err)
+	}
+	if sheet.Commands[0].Complexity != "beginner" {
+		t.Errorf("Expected complexity normalized to 'beginner', got '%s'", sheet.Commands[0].Complexity)
+	}
+
+	bad := filepath.Join(dir, "bad.yaml")
+	os.WriteFile(bad, []byte(`title: Bad
+commands:
+  - name: one
+    complexity: expert
+`), 0644)
+	if _, err := LoadCheatSheet(bad); err == nil || !strings.Contains(err.Error(), `unknown complexity "expert"`) {
+		t.Errorf("Expected an unknown complexity error, got %v", err)
+	}
+}
+
+func TestComplexityBadgeInList(t *testing.T) {
+	row := renderCommandRow(0, Command{Name: "x", Complexity: "advanced"}, false)
+	if !strings.Contains(row, "adv") {
+		t.Errorf("Expected an 'adv' badge in %q", row)
+	}
+	if row := renderCommandRow(0, Command{Name: "x"}, false); strings.Contains(row, "adv") {
+		t.Errorf("Expected no badge without complexity, got %q", row)
+	}
+}
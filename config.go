@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the user's configuration file
+type config struct {
+	Vars map[string]string `yaml:"vars"` // values of sheet variables, over the sheets' own
+}
+
+// configDir returns the directory cheatcheat reads its configuration from,
+// following the XDG base directory spec
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "cheatcheat"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "cheatcheat"), nil
+}
+
+// loadConfig reads the configuration file. A missing file is not an error and
+// yields the zero config.
+func loadConfig() (config, error) {
+	var cfg config
+	dir, err := configDir()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	err = yaml.Unmarshal(data, &cfg)
+	return cfg, err
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sync/errgroup"
+)
+
+// Deprecation marks a command that shouldn't be used any more
+type Deprecation struct {
+	Since       string `yaml:"since,omitempty" json:"since,omitempty" toml:"since,omitempty"`                   // tool version that deprecated it
+	Replacement string `yaml:"replacement,omitempty" json:"replacement,omitempty" toml:"replacement,omitempty"` // name of the command to use instead
+}
+
+// knownPlatforms are the values platforms may list, named like GOOS
+var knownPlatforms = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "linux", "netbsd", "openbsd", "plan9", "solaris", "windows"}
+
+// versionPattern finds a dotted version number, as in "git version 2.39.2"
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+|\d+`)
+
+// parseVersion splits a version like "2.23" or "v1.27.3" into its numbers
+func parseVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, field := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// compareVersions compares two parsed versions part by part, a missing part
+// counting as 0
+func compareVersions(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// validateConstraints checks the platforms and versions a command declares
+func validateConstraints(cmd Command) []error {
+	var errs []error
+	for _, platform := range cmd.Platforms {
+		if !containsString(knownPlatforms, platform) {
+			errs = append(errs, fmt.Errorf("command %q: unknown platform %q (want one of %s)",
+				cmd.Name, platform, strings.Join(knownPlatforms, ", ")))
+		}
+	}
+	for _, field := range []struct{ key, version string }{{"minVersion", cmd.MinVersion}, {"maxVersion", cmd.MaxVersion}} {
+		if _, ok := parseVersion(field.version); field.version != "" && !ok {
+			errs = append(errs, fmt.Errorf("command %q: %s %q is not a version like 2.23", cmd.Name, field.key, field.version))
+		}
+	}
+	return errs
+}
+
+// commandTool returns the program a command runs: the first word of its name
+func commandTool(cmd Command) string {
+	if fields := strings.Fields(cmd.Name); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// matchesPlatform reports whether cmd applies on platform. Commands that
+// don't list platforms apply everywhere.
+func matchesPlatform(cmd Command, platform string) bool {
+	return len(cmd.Platforms) == 0 || containsString(cmd.Platforms, platform)
+}
+
+// matchesVersion reports whether version lies between the minVersion and
+// maxVersion of cmd, both included. An unknown version matches.
+func matchesVersion(cmd Command, version string) bool {
+	v, ok := parseVersion(version)
+	if !ok {
+		return true
+	}
+	if lowest, ok := parseVersion(cmd.MinVersion); ok && compareVersions(v, lowest) < 0 {
+		return false
+	}
+	if highest, ok := parseVersion(cmd.MaxVersion); ok && compareVersions(v, highest) > 0 {
+		return false
+	}
+	return true
+}
+
+// versionRange describes the versions of its tool a command needs, or ""
+func versionRange(cmd Command) string {
+	tool := commandTool(cmd)
+	switch {
+	case cmd.MinVersion != "" && cmd.MaxVersion != "":
+		return fmt.Sprintf("%s %s – %s", tool, cmd.MinVersion, cmd.MaxVersion)
+	case cmd.MinVersion != "":
+		return fmt.Sprintf("%s ≥ %s", tool, cmd.MinVersion)
+	case cmd.MaxVersion != "":
+		return fmt.Sprintf("%s ≤ %s", tool, cmd.MaxVersion)
+	}
+	return ""
+}
+
+// versionTimeout bounds how long "<tool> --version" may run
+const versionTimeout = 2 * time.Second
+
+// toolVersionsMsg reports the versions of installed tools, "" for tools that
+// aren't installed or don't print a version
+type toolVersionsMsg map[string]string
+
+// isBareTool reports whether tool is a program name without a directory.
+// Only those are asked for their version, so a shared or included sheet can't
+// make cheatcheat run a program by path.
+func isBareTool(tool string) bool {
+	return tool != "" && !strings.ContainsAny(tool, `/\`)
+}
+
+// runToolVersion runs "<tool> --version", tool found on $PATH, and picks the
+// version out of what it prints; a variable so tests can stand in for it
+var runToolVersion = func(tool string) string {
+	if !isBareTool(tool) {
+		return ""
+	}
+	path, err := lookPath(tool)
+	if err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return versionPattern.FindString(string(out))
+}
+
+// detectToolVersions returns a command that finds the installed versions of
+// the tools of the commands that declare a version range, skipping those in
+// known
+func detectToolVersions(commands []Command, known map[string]string) tea.Cmd {
+	var tools []string
+	for _, cmd := range commands {
+		tool := commandTool(cmd)
+		if _, ok := known[tool]; ok || !isBareTool(tool) || versionRange(cmd) == "" || containsString(tools, tool) {
+			continue
+		}
+		tools = append(tools, tool)
+	}
+	if len(tools) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		versions := make([]string, len(tools))
+		var g errgroup.Group
+		for i, tool := range tools {
+			g.Go(func() error {
+				versions[i] = runToolVersion(tool)
+				return nil
+			})
+		}
+		g.Wait()
+		msg := make(toolVersionsMsg)
+		for i, tool := range tools {
+			msg[tool] = versions[i]
+		}
+		return msg
+	}
+}
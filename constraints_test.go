@@ -0,0 +1,231 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.23", "2.23", 0},
+		{"2.23", "2.23.0", 0},
+		{"2.9", "2.23", -1},
+		{"v1.27.3", "1.27", 1},
+		{"10", "9.99", 1},
+	}
+	for _, tt := range tests {
+		a, okA := parseVersion(tt.a)
+		b, okB := parseVersion(tt.b)
+		if !okA || !okB {
+			t.Fatalf("parseVersion(%q, %q) failed", tt.a, tt.b)
+		}
+		if got := compareVersions(a, b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "2.x", "latest", "2..3"} {
+		if _, ok := parseVersion(bad); ok {
+			t.Errorf("parseVersion(%q) succeeded, want failure", bad)
+		}
+	}
+}
+
+func TestMatchesConstraints(t *testing.T) {
+	cmd := Command{Name: "git switch", Platforms: []string{"linux", "darwin"}, MinVersion: "2.23", MaxVersion: "2.40"}
+	if !matchesPlatform(cmd, "linux") || matchesPlatform(cmd, "windows") {
+		t.Error("expected git switch on linux only, not windows")
+	}
+	if !matchesPlatform(Command{Name: "ls"}, "windows") {
+		t.Error("expected a command without platforms to match everywhere")
+	}
+	for version, want := range map[string]bool{
+		"2.22.1": false,
+		"2.23":   true,
+		"2.39.2": true,
+		"2.40":   true,
+		"2.41":   false,
+		"":       true, // not installed, or no version printed
+	} {
+		if got := matchesVersion(cmd, version); got != want {
+			t.Errorf("matchesVersion(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestValidateConstraints(t *testing.T) {
+	sheet := CheatSheet{Title: "t", Commands: []Command{
+		{Name: "a", ShortDesc: "a", Syntax: "a", Platforms: []string{"linux", "macos"}, MinVersion: "two"},
+	}}
+	err := validateSheet(&sheet)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{`unknown platform "macos"`, `minVersion "two" is not a version`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+// constraintsModel loads a sheet with a deprecated command and commands bound
+// to a platform and a tool version
+func constraintsModel(t *testing.T) model {
+	t.Helper()
+	sheet := CheatSheet{Title: "Git", Commands: []Command{
+		{Name: "git checkout", ShortDesc: "switch branches", Deprecated: &Deprecation{Since: "2.23", Replacement: "git switch"}},
+		{Name: "git switch", ShortDesc: "switch branches", MinVersion: "2.23"},
+		{Name: "git maintenance", ShortDesc: "run maintenance", MinVersion: "2.30"},
+		{Name: "pbcopy", ShortDesc: "copy to the clipboard", Platforms: []string{"plan9"}},
+	}}
+	var tm tea.Model = initialModel("", "cheatsheets")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(cheatSheetLoadedMsg(sheet))
+	return tm.(model)
+}
+
+func TestPlatformAndVersionFilters(t *testing.T) {
+	saved := runToolVersion
+	defer func() { runToolVersion = saved }()
+	var mu sync.Mutex
+	var asked []string
+	runToolVersion = func(tool string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		asked = append(asked, tool)
+		return "2.25.1"
+	}
+
+	m := constraintsModel(t)
+	m = press(m, runes("p"))
+	if names := commandNames(m.commands); containsString(names, "pbcopy") || len(names) != 3 {
+		t.Fatalf("expected pbcopy hidden on this platform, got %v", names)
+	}
+
+	tm, cmd := m.Update(runes("V"))
+	m = tm.(model)
+	if cmd == nil {
+		t.Fatal("expected V to look up tool versions")
+	}
+	tm, _ = m.Update(cmd())
+	m = tm.(model)
+	if !reflect.DeepEqual(asked, []string{"git"}) {
+		t.Errorf("expected only git to be asked for its version, asked %v", asked)
+	}
+	if got := commandNames(m.commands); !reflect.DeepEqual(got, []string{"git checkout", "git switch"}) {
+		t.Errorf("expected git maintenance hidden by git 2.25.1, got %v", got)
+	}
+	if status := ansi.Strip(RenderFilterStatus(m.commandFilter(), false, "", false, len(m.commands), 100)); !strings.Contains(status, "Installed versions") {
+		t.Errorf("expected the status to mention the version filter, got %q", status)
+	}
+
+	// Known versions aren't looked up again
+	m = press(m, runes("V"))
+	if _, cmd = m.Update(runes("V")); cmd != nil {
+		t.Error("expected no second version lookup")
+	}
+}
+
+func TestGoToReplacement(t *testing.T) {
+	m := constraintsModel(t)
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.showDetail {
+		t.Fatal("expected the detail view")
+	}
+	detail := ansi.Strip(m.viewport.View())
+	if !strings.Contains(detail, "Deprecated since 2.23 → use git switch") {
+		t.Errorf("expected the deprecation notice, got:\n%s", detail)
+	}
+
+	// Even when a filter hides it
+	m.searchQuery = "checkout"
+	m.applyFilters()
+	m = press(m, runes("r"))
+	if !m.showDetail || m.commands[m.currentCommand].Name != "git switch" {
+		t.Fatalf("expected r to open git switch, got %q", m.commands[m.currentCommand].Name)
+	}
+	if detail := ansi.Strip(m.viewport.View()); !strings.Contains(detail, "git ≥ 2.23") {
+		t.Errorf("expected the version range, got:\n%s", detail)
+	}
+}
+
+func TestMarkdownConstraints(t *testing.T) {
+	sheet := CheatSheet{Title: "Git", Commands: []Command{{
+		Name: "git checkout", ShortDesc: "switch branches", Syntax: "git checkout <branch>",
+		Aliases: []string{"git co"}, Keywords: []string{"switch branches"},
+		Platforms: []string{"linux", "darwin"}, MinVersion: "1.0", MaxVersion: "2.40",
+		Deprecated: &Deprecation{Since: "2.23", Replacement: "git switch"},
+	}}}
+	data, err := encodeMarkdownSheets([]CheatSheet{sheet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"Platforms: linux, darwin", "Min version: 1.0", "Max version: 2.40", "Deprecated: since 2.23; use git switch"} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, data)
+		}
+	}
+	sheets, err := decodeMarkdownSheets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sheets[0].Commands[0], sheet.Commands[0]) {
+		t.Errorf("round trip changed the command:\n got %+v\nwant %+v", sheets[0].Commands[0], sheet.Commands[0])
+	}
+}
+
+func TestLintDeprecated(t *testing.T) {
+	sheet := CheatSheet{Commands: []Command{
+		{Name: "git checkout", Deprecated: &Deprecation{Replacement: "git switch"}},
+		{Name: "git stash save", Deprecated: &Deprecation{Since: "2.16"}},
+	}}
+	want := []string{`command "git checkout": deprecated: no command called "git switch" to replace it`}
+	if got := lintDeprecated(sheet); !reflect.DeepEqual(got, want) {
+		t.Errorf("lintDeprecated() = %q, want %q", got, want)
+	}
+	sheet.Commands = append(sheet.Commands, Command{Name: "git switch"})
+	if got := lintDeprecated(sheet); got != nil {
+		t.Errorf("expected no problems once git switch exists, got %q", got)
+	}
+}
+
+func TestToolVersionOnlyBareNames(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	script := filepath.Join(dir, "evil")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if version := runToolVersion(script); version != "" {
+		t.Errorf("expected no version for a tool given by path, got %q", version)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("expected a tool given by path not to run")
+	}
+
+	saved := runToolVersion
+	defer func() { runToolVersion = saved }()
+	var asked []string
+	runToolVersion = func(tool string) string {
+		asked = append(asked, tool)
+		return ""
+	}
+	commands := []Command{
+		{Name: script + " --now", MinVersion: "1.0"},
+		{Name: `..\evil.exe`, MinVersion: "1.0"},
+		{Name: "git switch", MinVersion: "2.23"},
+	}
+	detectToolVersions(commands, nil)()
+	if !reflect.DeepEqual(asked, []string{"git"}) {
+		t.Errorf("expected only git to be asked for its version, asked %v", asked)
+	}
+}
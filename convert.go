@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runConvert implements "cheatcheat convert": it reads a sheet in any
+// registered format and writes it in another, picked with --to or by the
+// extension of the -o file. Extends and include are kept as they are written,
+// not resolved.
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	to := flags.String("to", "", "output format: "+formatNames())
+	out := flags.String("o", "", "file to write, instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat convert [--to format] [-o file] sheet")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || (*to == "" && *out == "") {
+		flags.Usage()
+		return 2
+	}
+
+	format, ok := formatByName(*to)
+	if *to == "" {
+		format, ok = formatForPath(*out)
+	}
+	if !ok {
+		fmt.Fprintf(stderr, "unknown output format, want one of %s\n", formatNames())
+		return 2
+	}
+
+	sheets, err := decodeSheetFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for i := range sheets {
+		if err := validateSheet(&sheets[i]); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), documentError(i, err))
+			return 1
+		}
+	}
+	data, err := format.encode(sheets)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	if *out == "" {
+		stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sheetDocument is a cheatsheet file kept as its original text together with
+// its yaml.Node tree. Edits replace only the lines of the commands they touch,
+// so comments, quoting and blank lines elsewhere survive a save untouched, and
+// saving an unedited document writes back the very bytes it was loaded from.
+type sheetDocument struct {
+	lines []string // the file split after each newline
+	root  *yaml.Node
+}
+
+// loadSheetDocument reads the cheatsheet at path for editing. Only YAML
+// sheets can be edited in place.
+func loadSheetDocument(path string) (*sheetDocument, error) {
+	if format, _ := formatForPath(path); format.name != "yaml" {
+		return nil, fmt.Errorf("%s: only YAML sheets can be edited in place, not %s", path, format.name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseSheetDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// parseSheetDocument parses the text of a cheatsheet for editing
+func parseSheetDocument(data []byte) (*sheetDocument, error) {
+	doc := &sheetDocument{}
+	if err := doc.setText(data); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// setText replaces the document's text and re-parses it so node positions
+// match the new lines
+func (d *sheetDocument) setText(data []byte) error {
+	var root, next yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&root); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err := dec.Decode(&next); !errors.Is(err, io.EOF) {
+		return errors.New("files holding several sheets can't be edited in place")
+	}
+	if len(root.Content) == 0 {
+		// An empty file: start an empty mapping
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("a cheatsheet must be a mapping, got %s", nodeKindName(root.Content[0]))
+	}
+	d.root = &root
+	d.lines = strings.SplitAfter(string(data), "\n")
+	if d.lines[len(d.lines)-1] == "" {
+		d.lines = d.lines[:len(d.lines)-1]
+	}
+	return nil
+}
+
+// Bytes returns the document's current text
+func (d *sheetDocument) Bytes() []byte {
+	return []byte(strings.Join(d.lines, ""))
+}
+
+// Save writes the document to path
+func (d *sheetDocument) Save(path string) error {
+	return os.WriteFile(path, d.Bytes(), 0644)
+}
+
+// Sheet decodes the document as it is now into the typed CheatSheet
+func (d *sheetDocument) Sheet() (CheatSheet, error) {
+	var sheet CheatSheet
+	err := d.root.Decode(&sheet)
+	return sheet, err
+}
+
+// commands returns the sequence node listing the sheet's commands, or nil
+func (d *sheetDocument) commands() *yaml.Node {
+	return mappingValue(d.root.Content[0], "commands")
+}
+
+// commandIndex returns the position of the command called name, or -1
+func (d *sheetDocument) commandIndex(name string) int {
+	commands := d.commands()
+	if commands == nil {
+		return -1
+	}
+	for i, item := range commands.Content {
+		if n := mappingValue(item, "name"); n != nil && n.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetCommand replaces the command called name with cmd, or appends cmd to the
+// sheet when name is empty or no command has that name. Keys and comments of
+// the replaced command are kept wherever their values didn't change.
+func (d *sheetDocument) SetCommand(name string, cmd Command) error {
+	idx := -1
+	if name != "" {
+		idx = d.commandIndex(name)
+	}
+	if idx >= 0 {
+		item := d.commands().Content[idx]
+		start, end := d.commandSpan(idx)
+		lines, err := renderCommandLines(cmd, item, d.dashIndent(idx), d.quoting())
+		if err != nil {
+			return err
+		}
+		return d.splice(start, end, lines)
+	}
+	return d.appendCommand(cmd)
+}
+
+// DeleteCommand removes the command called name, along with the blank line
+// that separated it from its neighbours
+func (d *sheetDocument) DeleteCommand(name string) error {
+	idx := d.commandIndex(name)
+	if idx < 0 {
+		return fmt.Errorf("no command called %q", name)
+	}
+	return d.splice(d.deletionSpan(idx))
+}
+
+// MoveCommand moves the command called name to position to in the list of
+// commands. Comments above other commands stay with them.
+func (d *sheetDocument) MoveCommand(name string, to int) error {
+	idx := d.commandIndex(name)
+	if idx < 0 {
+		return fmt.Errorf("no command called %q", name)
+	}
+	count := len(d.commands().Content)
+	if to < 0 || to >= count {
+		return fmt.Errorf("cannot move %q to position %d of %d", name, to, count)
+	}
+	if to == idx {
+		return nil
+	}
+
+	start, end := d.commandSpan(idx)
+	lines := append([]string(nil), d.lines[start:end]...)
+	if err := d.splice(d.deletionSpan(idx)); err != nil {
+		return err
+	}
+	if to == count-1 {
+		return d.appendLines(lines)
+	}
+	return d.insertLines(to, lines)
+}
+
+// AddTag adds tag to the command called name unless it already has it
+func (d *sheetDocument) AddTag(name, tag string) error {
+	idx := d.commandIndex(name)
+	if idx < 0 {
+		return fmt.Errorf("no command called %q", name)
+	}
+	var cmd Command
+	if err := d.commands().Content[idx].Decode(&cmd); err != nil {
+		return err
+	}
+	if containsString(cmd.Tags, tag) {
+		return nil
+	}
+	cmd.Tags = append(cmd.Tags, tag)
+	return d.SetCommand(name, cmd)
+}
+
+// deletionSpan returns the splice that removes command idx: its lines, plus
+// one of the blank lines around it when that would leave two in a row
+func (d *sheetDocument) deletionSpan(idx int) (start, end int, lines []string) {
+	start, end = d.commandSpan(idx)
+	blankBefore := start > 0 && strings.TrimSpace(d.lines[start-1]) == ""
+	blankAfter := end >= len(d.lines) || strings.TrimSpace(d.lines[end]) == ""
+	if blankBefore && blankAfter {
+		start--
+	}
+	return start, end, nil
+}
+
+// separated reports whether the commands are set apart by blank lines
+func (d *sheetDocument) separated() bool {
+	commands := d.commands()
+	for i := 1; i < len(commands.Content); i++ {
+		if line := commands.Content[i].Line - 2; line >= 0 && strings.TrimSpace(d.lines[line]) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// appendCommand adds cmd after the last command, separated from it the same
+// way the existing commands are separated from each other
+func (d *sheetDocument) appendCommand(cmd Command) error {
+	commands := d.commands()
+	if commands == nil || commands.Style&yaml.FlowStyle != 0 || len(commands.Content) == 0 {
+		return d.rewriteWithCommand(cmd)
+	}
+
+	last := len(commands.Content) - 1
+	lines, err := renderCommandLines(cmd, nil, d.dashIndent(last), d.quoting())
+	if err != nil {
+		return err
+	}
+	return d.appendLines(lines)
+}
+
+// appendLines adds the lines of a command after the last command
+func (d *sheetDocument) appendLines(lines []string) error {
+	_, end := d.commandSpan(len(d.commands().Content) - 1)
+	if d.separated() {
+		lines = append([]string{"\n"}, lines...)
+	}
+	if end > 0 && !strings.HasSuffix(d.lines[end-1], "\n") {
+		// The file didn't end in a newline
+		d.lines[end-1] += "\n"
+	}
+	return d.splice(end, end, lines)
+}
+
+// insertLines adds the lines of a command in front of command idx and the
+// comments directly above it
+func (d *sheetDocument) insertLines(idx int, lines []string) error {
+	at, _ := d.commandSpan(idx)
+	indent := d.dashIndent(idx)
+	for at > 0 {
+		line := d.lines[at-1]
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") || len(line)-len(strings.TrimLeft(line, " ")) > indent {
+			break
+		}
+		at--
+	}
+	if d.separated() {
+		lines = append(lines, "\n")
+	}
+	return d.splice(at, at, lines)
+}
+
+// rewriteWithCommand adds cmd to a sheet without a block list of commands to
+// extend, re-encoding the whole document. Only empty or unusually formatted
+// sheets take this path.
+func (d *sheetDocument) rewriteWithCommand(cmd Command) error {
+	node, err := commandNode(cmd, nil, d.quoting())
+	if err != nil {
+		return err
+	}
+	mapping := d.root.Content[0]
+	commands := mappingValue(mapping, "commands")
+	if commands == nil {
+		commands = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "commands"}, commands)
+	}
+	commands.Kind = yaml.SequenceNode
+	commands.Style = 0
+	commands.Content = append(commands.Content, node)
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return err
+	}
+	return d.setText(b.Bytes())
+}
+
+// quoting returns how the sheet quotes its strings, judged by the name of
+// its first command, so new values blend in
+func (d *sheetDocument) quoting() yaml.Style {
+	if commands := d.commands(); commands != nil && len(commands.Content) > 0 {
+		if name := mappingValue(commands.Content[0], "name"); name != nil {
+			return name.Style
+		}
+	}
+	return 0
+}
+
+// splice replaces lines [start, end) with lines and re-parses the result
+func (d *sheetDocument) splice(start, end int, lines []string) error {
+	text := make([]string, 0, len(d.lines)-(end-start)+len(lines))
+	text = append(text, d.lines[:start]...)
+	text = append(text, lines...)
+	text = append(text, d.lines[end:]...)
+	return d.setText([]byte(strings.Join(text, "")))
+}
+
+// dashIndent returns the column of the "-" starting command idx
+func (d *sheetDocument) dashIndent(idx int) int {
+	line := d.lines[d.commands().Content[idx].Line-1]
+	if i := strings.Index(line, "-"); i >= 0 {
+		return i
+	}
+	return 0
+}
+
+// commandSpan returns the lines [start, end) holding command idx, from its
+// "-" line to its last line. Blank lines and comments at the list's own
+// indentation that follow it belong to whatever comes next.
+func (d *sheetDocument) commandSpan(idx int) (start, end int) {
+	commands := d.commands()
+	start = commands.Content[idx].Line - 1
+	end = len(d.lines)
+	if idx+1 < len(commands.Content) {
+		end = commands.Content[idx+1].Line - 1
+	} else if next := d.keyAfter("commands"); next != nil {
+		end = next.Line - 1
+	}
+
+	indent := d.dashIndent(idx)
+	for end > start+1 {
+		line := d.lines[end-1]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !(strings.HasPrefix(trimmed, "#") && len(line)-len(strings.TrimLeft(line, " ")) <= indent) {
+			break
+		}
+		end--
+	}
+	return start, end
+}
+
+// keyAfter returns the key node following key in the top-level mapping, or nil
+func (d *sheetDocument) keyAfter(key string) *yaml.Node {
+	mapping := d.root.Content[0]
+	for i := 0; i+2 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+2]
+		}
+	}
+	return nil
+}
+
+// commandFields lists the keys of a command in the order of the Command struct
+var commandFields = []string{"name", "shortDesc", "syntax", "tags", "complexity", "examples", "notes", "options", "related",
+	"aliases", "keywords", "platforms", "minVersion", "maxVersion", "deprecated"}
+
+// commandNode builds the mapping node for cmd, leaving out empty fields and
+// quoting strings with the given style. When old is the node cmd replaces,
+// unchanged values keep their old nodes, with their comments and styles, and
+// the old key order is kept.
+func commandNode(cmd Command, old *yaml.Node, style yaml.Style) (*yaml.Node, error) {
+	var fresh yaml.Node
+	if err := fresh.Encode(cmd); err != nil {
+		return nil, err
+	}
+	values := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(fresh.Content); i += 2 {
+		if value := fresh.Content[i+1]; !emptyNode(value) {
+			values[fresh.Content[i].Value] = value
+		}
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	add := func(key *yaml.Node, value *yaml.Node) {
+		node.Content = append(node.Content, key, value)
+	}
+
+	done := make(map[string]bool)
+	if old != nil {
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			key, oldValue := old.Content[i], old.Content[i+1]
+			value, ok := values[key.Value]
+			if !ok {
+				if !containsString(commandFields, key.Value) {
+					// Keys the Command struct doesn't know about are kept
+					add(key, oldValue)
+				}
+				continue
+			}
+			done[key.Value] = true
+			if sameValue(value, oldValue) {
+				add(key, oldValue)
+				continue
+			}
+			styleLike(value, oldValue, style)
+			value.LineComment = oldValue.LineComment
+			add(key, value)
+		}
+	}
+	for _, field := range commandFields {
+		value, ok := values[field]
+		if !ok || done[field] {
+			continue
+		}
+		styleLike(value, nil, style)
+		if field == "tags" || field == "related" {
+			value.Style = yaml.FlowStyle
+		}
+		add(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field}, value)
+	}
+	return node, nil
+}
+
+// renderCommandLines renders cmd as a list item whose "-" sits at column
+// indent, reusing what it can of old
+func renderCommandLines(cmd Command, old *yaml.Node, indent int, quoting yaml.Style) ([]string, error) {
+	node, err := commandNode(cmd, old, quoting)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.Content) > 0 {
+		// Comments above the item stay where they are in the file
+		node.Content[0].HeadComment = ""
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	enc.Close()
+
+	pad := strings.Repeat(" ", indent)
+	lines := strings.SplitAfter(strings.TrimRight(b.String(), "\n")+"\n", "\n")
+	lines = lines[:len(lines)-1]
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = pad + "- " + line
+		case strings.TrimSpace(line) == "":
+			lines[i] = line
+		default:
+			lines[i] = pad + "  " + line
+		}
+	}
+	return lines, nil
+}
+
+// emptyNode reports whether an encoded value is empty and can be left out
+func emptyNode(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value == "" && n.Tag == "!!str"
+	case yaml.SequenceNode, yaml.MappingNode:
+		return len(n.Content) == 0
+	}
+	return false
+}
+
+// sameValue reports whether two nodes decode to the same data
+func sameValue(a, b *yaml.Node) bool {
+	var va, vb any
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// styleLike gives a freshly encoded value the look of the value it replaces,
+// or of the rest of the sheet: strings quoted like the command names, lists
+// in flow style if the old list was
+func styleLike(n, old *yaml.Node, quoting yaml.Style) {
+	if old != nil && n.Kind == old.Kind && n.Kind == yaml.SequenceNode {
+		n.Style = old.Style & yaml.FlowStyle
+	}
+	var walk func(*yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && !strings.Contains(n.Value, "\n") {
+			n.Style = quoting
+		}
+		for _, child := range n.Content {
+			if child.Kind == yaml.ScalarNode && n.Kind == yaml.MappingNode && isMappingKey(n, child) {
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+}
+
+// isMappingKey reports whether child sits in a key position of mapping
+func isMappingKey(mapping, child *yaml.Node) bool {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i] == child {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeKindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		return "a scalar"
+	case yaml.AliasNode:
+		return "an alias"
+	}
+	return "a mapping"
+}
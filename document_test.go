@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestDocumentRoundTrip(t *testing.T) {
+	sheets, err := DiscoverCheatsheets("cheatsheets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"testdata/document/sheet.yaml"}
+	for _, sheet := range sheets {
+		paths = append(paths, filepath.Join("cheatsheets", sheet))
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			original, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := loadSheetDocument(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Rewrite every command with itself, through the same path as
+			// a real edit
+			sheet, err := doc.Sheet()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, cmd := range sheet.Commands {
+				if err := doc.SetCommand(cmd.Name, cmd); err != nil {
+					t.Fatal(err)
+				}
+			}
+			saved := filepath.Join(t.TempDir(), "sheet.yaml")
+			if err := doc.Save(saved); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, original) {
+				removed, added := diffLines(string(original), string(got))
+				t.Errorf("Expected a byte-identical save, got -%q +%q", removed, added)
+			}
+		})
+	}
+}
+
+// TestDocumentEdits applies each edit to testdata/document/sheet.yaml and
+// compares the result with the matching .golden file. Run with -update to
+// rewrite the golden files after an intended change.
+func TestDocumentEdits(t *testing.T) {
+	edits := map[string]func(*sheetDocument) error{
+		"update": func(d *sheetDocument) error {
+			return d.SetCommand("zip", Command{
+				Name:      "zip",
+				ShortDesc: "Create or update a zip archive",
+				Syntax:    "zip -r <archive> <files>",
+				Tags:      []string{"archive"},
+				Related:   []string{"unzip", "tar create"},
+			})
+		},
+		"add": func(d *sheetDocument) error {
+			return d.SetCommand("", Command{
+				Name:       "7z",
+				ShortDesc:  "Work with 7-Zip archives",
+				Tags:       []string{"archive"},
+				Complexity: "intermediate",
+				Options:    []Option{{Flag: "a", Description: "Add files to an archive"}},
+			})
+		},
+		"delete": func(d *sheetDocument) error {
+			return d.DeleteCommand("zip")
+		},
+		"delete-last": func(d *sheetDocument) error {
+			return d.DeleteCommand("unzip")
+		},
+		"move-first": func(d *sheetDocument) error {
+			return d.MoveCommand("unzip", 0)
+		},
+		"move-last": func(d *sheetDocument) error {
+			return d.MoveCommand("tar create", 3)
+		},
+		"add-tag": func(d *sheetDocument) error {
+			return d.AddTag("tar create", "backup")
+		},
+	}
+
+	for name, apply := range edits {
+		t.Run(name, func(t *testing.T) {
+			doc, err := loadSheetDocument("testdata/document/sheet.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := apply(doc); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := doc.Sheet(); err != nil {
+				t.Fatalf("Edited document doesn't decode: %v", err)
+			}
+
+			golden := filepath.Join("testdata", "document", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, doc.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := doc.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("Edit doesn't match %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestDocumentEditErrors(t *testing.T) {
+	doc, err := loadSheetDocument("testdata/document/sheet.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeleteCommand("missing"); err == nil {
+		t.Error("Expected deleting an unknown command to fail")
+	}
+	if err := doc.MoveCommand("zip", 9); err == nil {
+		t.Error("Expected moving past the end to fail")
+	}
+	before := doc.Bytes()
+	if err := doc.AddTag("zip", "archive"); err != nil || !bytes.Equal(doc.Bytes(), before) {
+		t.Errorf("Expected adding a present tag to change nothing, got %v", err)
+	}
+}
+
+// diffLines returns the lines of a and b that differ, assuming b only
+// replaced lines of a in place
+func diffLines(a, b string) (removed, added []string) {
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for len(al) > 0 && len(bl) > 0 && al[0] == bl[0] {
+		al, bl = al[1:], bl[1:]
+	}
+	for len(al) > 0 && len(bl) > 0 && al[len(al)-1] == bl[len(bl)-1] {
+		al, bl = al[:len(al)-1], bl[:len(bl)-1]
+	}
+	return al, bl
+}
+
+func TestSetCommandUpdatesInPlace(t *testing.T) {
+	original, err := os.ReadFile("cheatsheets/git.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseSheetDocument(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet, err := LoadCheatSheet("cheatsheets/git.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := sheet.Commands[1]
+	cmd.ShortDesc = "Create an empty Git repository"
+	if err := doc.SetCommand(cmd.Name, cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, added := diffLines(string(original), string(doc.Bytes()))
+	if len(removed) != 1 || len(added) != 1 {
+		t.Fatalf("Expected a one line change, got -%q +%q", removed, added)
+	}
+	if want := `    shortDesc: "Create an empty Git repository"`; added[0] != want {
+		t.Errorf("Expected %q, got %q", want, added[0])
+	}
+}
+
+func TestSetCommandAppends(t *testing.T) {
+	original, err := os.ReadFile("cheatsheets/git.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "git.yaml")
+	doc, err := parseSheetDocument(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := Command{
+		Name:      "git sparse-checkout",
+		ShortDesc: "Check out only part of a repository",
+		Tags:      []string{"repository"},
+		Examples:  []Example{{Code: "git sparse-checkout set docs", Description: "Only check out docs/"}},
+	}
+	if err := doc.SetCommand("", cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	text := string(doc.Bytes())
+	if !strings.HasPrefix(text, string(original)) {
+		t.Error("Expected the original text to be kept ahead of the new command")
+	}
+	if want := "\n  - name: \"git sparse-checkout\"\n    shortDesc:"; !strings.Contains(text, want) {
+		t.Errorf("Expected the new command quoted and indented like the others, got:\n%s", text[len(original):])
+	}
+
+	sheet, err := LoadCheatSheet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := sheet.Commands[len(sheet.Commands)-1]
+	if last.Name != cmd.Name || len(last.Examples) != 1 || last.Tags[0] != "repository" {
+		t.Errorf("Expected the appended command to load back, got %+v", last)
+	}
+}
+
+func TestSetCommandKeepsComments(t *testing.T) {
+	doc, err := parseSheetDocument([]byte(editFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetCommand("first", Command{Name: "first", ShortDesc: "Changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.Bytes()), "  # a comment between commands\n  - name: second") {
+		t.Errorf("Expected the comment between commands kept, got:\n%s", doc.Bytes())
+	}
+
+	// Starting from an empty file
+	doc, err = parseSheetDocument(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetCommand("", Command{Name: "only"}); err != nil {
+		t.Fatal(err)
+	}
+	if doc.commandIndex("only") != 0 {
+		t.Errorf("Expected the command added to an empty sheet, got:\n%s", doc.Bytes())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEditor is run when $EDITOR isn't set
+const defaultEditor = "vi"
+
+// editorFinishedMsg reports that the editor opened on a sheet has exited
+type editorFinishedMsg struct {
+	path    string // sheet that was edited
+	command string // name of the command to select again after reloading
+	err     error  // set when the editor couldn't be run or failed
+}
+
+// commandLine returns the 1-based line on which the command called name
+// starts in document number index of the YAML stream data, or 0 when it
+// can't be found
+func commandLine(data []byte, index int, name string) int {
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 0; i <= index; i++ {
+		doc = yaml.Node{}
+		if err := dec.Decode(&doc); err != nil {
+			return 0
+		}
+	}
+	if len(doc.Content) == 0 {
+		return 0
+	}
+	commands := mappingValue(doc.Content[0], "commands")
+	if commands == nil || commands.Kind != yaml.SequenceNode {
+		return 0
+	}
+	for _, item := range commands.Content {
+		if n := mappingValue(item, "name"); n != nil && n.Value == name {
+			return item.Line
+		}
+	}
+	return 0
+}
+
+// mappingValue returns the value stored under key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// errorLinePattern finds the line number yaml.v3 puts in its error messages
+var errorLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// errorLine returns the line a parse error points at, or 0 when it names none
+func errorLine(err error) int {
+	match := errorLinePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
+
+// editorCommand builds the command that opens path in $EDITOR at line. The
+// "+line" argument is understood by vi, vim, nano, emacs and most others.
+func editorCommand(path string, line int) *exec.Cmd {
+	args := strings.Fields(os.Getenv("EDITOR"))
+	if len(args) == 0 {
+		args = []string{defaultEditor}
+	}
+	if line > 0 {
+		args = append(args, fmt.Sprintf("+%d", line))
+	}
+	args = append(args, path)
+	return exec.Command(args[0], args[1:]...)
+}
+
+// editSheet suspends the TUI and opens the file at path in $EDITOR, on the
+// given line, or on the line of the named command of sheet number doc when
+// line is 0
+func editSheet(path string, doc int, command string, line int) tea.Cmd {
+	if format, _ := formatForPath(path); line == 0 && format.commandLine != nil {
+		if data, err := os.ReadFile(path); err == nil {
+			line = format.commandLine(data, doc, command)
+		}
+	}
+	return tea.ExecProcess(editorCommand(path, line), func(err error) tea.Msg {
+		return editorFinishedMsg{path: path, command: command, err: err}
+	})
+}
+
+// editSelected opens the sheet in $EDITOR at the selected command. A command
+// the sheet inherits or includes is opened in the sheet it comes from.
+func (m model) editSelected() tea.Cmd {
+	if m.cheatSheet.Path == "" {
+		return nil
+	}
+	if len(m.commands) == 0 {
+		return editSheet(m.cheatSheet.Path, m.cheatSheet.Document, "", 0)
+	}
+	cmd := m.commands[m.currentCommand]
+	if cmd.Source != "" {
+		return editSheet(cmd.Source, 0, cmd.Name, 0)
+	}
+	return editSheet(m.cheatSheet.Path, m.cheatSheet.Document, cmd.Name, 0)
+}
+
+// reloadEdited re-reads a sheet after it was edited. When it no longer parses
+// or validates, the error is kept so the user can edit again; otherwise the
+// edited command is selected again, in the view it was edited from.
+func (m *model) reloadEdited(msg editorFinishedMsg) {
+	m.editPath = msg.path
+	if msg.err != nil {
+		m.editErr = fmt.Errorf("running editor: %w", msg.err)
+		m.editCommand = msg.command
+		return
+	}
+	m.reloadSheet(m.cheatSheet.Path, msg.command)
+}
+
+// reloadSheet re-reads the sheet at path after it was changed and selects the
+// named command again, in the view it was changed from
+func (m *model) reloadSheet(path, command string) {
+	sheet, err := loadCheatSheetAt(path, m.cheatSheet.Document)
+	if err != nil {
+		m.editErr = err
+		m.editCommand = command
+		return
+	}
+
+	detail := m.showDetail
+	m.editErr = nil
+	m.setCheatSheet(sheet)
+	for i, cmd := range m.commands {
+		if cmd.Name == command {
+			m.selectCommand(i)
+			if detail {
+				m.openDetail()
+			}
+			break
+		}
+	}
+}
+
+// reEdit opens the file that was edited again after the edit left the sheet
+// broken, at the line the error points to when there is one. That is the sheet
+// itself, or the sheet an inherited or included command comes from.
+func (m model) reEdit() tea.Cmd {
+	if m.editPath != m.cheatSheet.Path {
+		return editSheet(m.editPath, 0, m.editCommand, errorLine(m.editErr))
+	}
+	return editSheet(m.cheatSheet.Path, m.cheatSheet.Document, m.editCommand, errorLine(m.editErr))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const editFixture = `title: Edit test
+commands:
+  - name: first
+    shortDesc: The first command
+  # a comment between commands
+  - name: second
+    shortDesc: The second command
+    tags: [b]
+  - name: third
+    shortDesc: The third command
+`
+
+func TestCommandLine(t *testing.T) {
+	data := []byte(editFixture)
+	for name, want := range map[string]int{"first": 3, "second": 6, "third": 9, "missing": 0} {
+		if got := commandLine(data, 0, name); got != want {
+			t.Errorf("commandLine(%q) = %d, want %d", name, got, want)
+		}
+	}
+	if got := commandLine([]byte("commands: [\n"), 0, "first"); got != 0 {
+		t.Errorf("Expected 0 for a broken document, got %d", got)
+	}
+}
+
+func TestErrorLine(t *testing.T) {
+	if got := errorLine(errors.New("yaml: line 7: could not find expected ':'")); got != 7 {
+		t.Errorf("Expected line 7, got %d", got)
+	}
+	if got := errorLine(errors.New(`command "x": unknown complexity "expert"`)); got != 0 {
+		t.Errorf("Expected no line, got %d", got)
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("EDITOR", "code --wait")
+	cmd := editorCommand("sheet.yaml", 12)
+	if want := []string{"code", "--wait", "+12", "sheet.yaml"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Expected %v, got %v", want, cmd.Args)
+	}
+
+	t.Setenv("EDITOR", "")
+	cmd = editorCommand("sheet.yaml", 0)
+	if want := []string{defaultEditor, "sheet.yaml"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("Expected %v, got %v", want, cmd.Args)
+	}
+}
+
+// editedModel loads the edit fixture from a temporary file and selects the
+// command called name
+func editedModel(t *testing.T, name string) (model, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sheet.yaml")
+	if err := os.WriteFile(path, []byte(editFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(path, filepath.Dir(path))
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(loadCheatSheetMsg(path, 0))
+	m = tm.(model)
+	for i, cmd := range m.commands {
+		if cmd.Name == name {
+			m.selectCommand(i)
+		}
+	}
+	return m, path
+}
+
+func TestReloadAfterEdit(t *testing.T) {
+	m, path := editedModel(t, "third")
+	m.openDetail()
+
+	edited := strings.Replace(editFixture, "The third command", "The fixed third command", 1)
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := m.Update(editorFinishedMsg{path: path, command: "third"})
+	m = tm.(model)
+
+	if m.editErr != nil {
+		t.Fatalf("Unexpected error: %v", m.editErr)
+	}
+	if got := m.commands[m.currentCommand]; got.Name != "third" || got.ShortDesc != "The fixed third command" {
+		t.Errorf("Expected the edited command selected, got %+v", got)
+	}
+	if !m.showDetail {
+		t.Error("Expected the detail view reopened")
+	}
+}
+
+func TestReloadAfterBrokenEdit(t *testing.T) {
+	m, path := editedModel(t, "second")
+
+	if err := os.WriteFile(path, []byte("title: [broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := m.Update(editorFinishedMsg{path: path, command: "second"})
+	m = tm.(model)
+
+	if m.editErr == nil {
+		t.Fatal("Expected the parse error to be kept")
+	}
+	if !strings.Contains(m.View(), "e: Edit again") {
+		t.Error("Expected the view to offer editing again")
+	}
+	if m.editCommand != "second" {
+		t.Errorf("Expected to return to command second, got %q", m.editCommand)
+	}
+
+	// Esc keeps the version loaded before the edit
+	m = press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.editErr != nil || len(m.cheatSheet.Commands) != 3 {
+		t.Errorf("Expected the previous sheet back, got %v with %d commands", m.editErr, len(m.cheatSheet.Commands))
+	}
+}
+
+func TestReloadAfterBrokenEditOfIncludedSheet(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"main.yaml":   "title: Main\ninclude: [common.yaml]\ncommands:\n  - name: own\n",
+		"common.yaml": "commands:\n  - name: shared\n",
+	})
+	path := filepath.Join(dir, "main.yaml")
+	m := initialModel(path, dir)
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(loadCheatSheetMsg(path, 0))
+	m = tm.(model)
+
+	included := filepath.Join(dir, "common.yaml")
+	if err := os.WriteFile(included, []byte("commands: [broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm, _ = m.Update(editorFinishedMsg{path: included, command: "shared"})
+	m = tm.(model)
+
+	if m.editErr == nil {
+		t.Fatal("Expected the parse error to be kept")
+	}
+	if m.editPath != included {
+		t.Errorf("Expected to edit %s again, got %q", included, m.editPath)
+	}
+	if !strings.Contains(m.View(), "common.yaml") {
+		t.Errorf("Expected the view to name the included sheet, got:\n%s", m.View())
+	}
+}
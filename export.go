@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// exporters are the targets of "cheatcheat export <target>", each with its
+// own flags. Unlike convert, exports are meant for reading elsewhere, so they
+// take sheets with extends and include resolved.
+var exporters = map[string]subcommand{
+	"html": {run: runExportHTML, flags: []commandFlag{{name: "o", value: true}}, args: argsSheets},
+	"anki": {run: runExportAnki, flags: []commandFlag{{name: "o", value: true}}, args: argsSheets},
+	"man":  {run: runExportMan, flags: []commandFlag{{name: "out", value: true}}, args: argsSheets},
+}
+
+// exporterNames lists the export targets for usage messages
+func exporterNames() string {
+	var names []string
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// runExport implements "cheatcheat export": it hands the arguments after the
+// target on to its exporter
+func runExport(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || exporters[args[0]].run == nil {
+		fmt.Fprintf(stderr, "usage: cheatcheat export target [flags] [paths...]\ntargets: %s\n", exporterNames())
+		return 2
+	}
+	return exporters[args[0]].run(args[1:], stdout, stderr)
+}
+
+// loadExportSheets loads the sheets found in paths, or in the cheatsheet
+// roots when there are none, in path order
+func loadExportSheets(paths []string) ([]CheatSheet, error) {
+	if len(paths) == 0 {
+		for _, root := range sheetRoots {
+			if _, err := os.Stat(root); err == nil {
+				paths = append(paths, root)
+			}
+		}
+	}
+	files, err := sheetPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	var sheets []CheatSheet
+	for _, file := range files {
+		loaded, err := LoadCheatSheets(file)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, loaded...)
+	}
+	return sheets, nil
+}
+
+// writeExport writes data to the file out, or to stdout when out is empty
+func writeExport(data []byte, out string, stdout io.Writer) error {
+	if out == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(out, data, 0644)
+}
+
+// runExportHTML implements "cheatcheat export html": one standalone page
+// holding every sheet
+func runExportHTML(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export html", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("o", "", "file to write, instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat export html [-o file] [paths...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	sheets, err := loadExportSheets(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	data, err := exportHTML(sheets)
+	if err == nil {
+		err = writeExport(data, *out, stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// htmlTemplate lays out the exported page. Example outputs are collapsible,
+// and start collapsed when they are longer than the detail view shows.
+var htmlTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"long": func(output string) bool { return outputLines(output) > maxOutputLines },
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ if eq (len .) 1 }}{{ (index . 0).Title }}{{ else }}Cheatsheets{{ end }}</title>
+<style>
+body { font-family: sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; }
+pre { background: #282828; color: #b8bb26; padding: .5rem 1rem; overflow-x: auto; }
+details.output pre { background: #f4f4f4; color: #666; }
+.tags, .output summary { color: #888; }
+</style>
+</head>
+<body>
+{{- range . }}
+<section>
+<h1>{{ .Title }}</h1>
+{{- if .Description }}
+<p>{{ .Description }}</p>
+{{- end }}
+{{- range .Commands }}
+<article>
+<h2>{{ .Name }}</h2>
+<p>{{ .ShortDesc }}</p>
+{{- if .Tags }}
+<p class="tags">Tags: {{ join .Tags ", " }}</p>
+{{- end }}
+{{- if .Syntax }}
+<pre><code>{{ .Syntax }}</code></pre>
+{{- end }}
+{{- if .Examples }}
+<h3>Examples</h3>
+{{- range .Examples }}
+{{- if .Description }}
+<p>{{ .Description }}</p>
+{{- end }}
+<pre><code>{{ .Code }}</code></pre>
+{{- if or .Output .ExitCode }}
+<details class="output"{{ if not (long .Output) }} open{{ end }}>
+<summary>Output{{ if .ExitCode }} (exit {{ .ExitCode }}){{ end }}</summary>
+{{- if .Output }}
+<pre><samp>{{ .Output }}</samp></pre>
+{{- end }}
+</details>
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if .Options }}
+<h3>Options</h3>
+<dl>
+{{- range .Options }}
+<dt><code>{{ .Flag }}</code></dt>
+<dd>{{ .Description }}</dd>
+{{- end }}
+</dl>
+{{- end }}
+{{- if .Notes }}
+<h3>Notes</h3>
+<ul>
+{{- range .Notes }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if .Related }}
+<h3>Related</h3>
+<p>{{ join .Related ", " }}</p>
+{{- end }}
+</article>
+{{- end }}
+</section>
+{{- end }}
+</body>
+</html>
+`))
+
+// exportHTML renders sheets as a standalone HTML page
+func exportHTML(sheets []CheatSheet) ([]byte, error) {
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, sheets); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// outputSheet has examples with a short output, a long one and an exit code
+func outputSheet() CheatSheet {
+	var long []string
+	for i := 1; i <= 10; i++ {
+		long = append(long, fmt.Sprintf("line %d", i))
+	}
+	return CheatSheet{Title: "Shell", Commands: []Command{{
+		Name: "ls", ShortDesc: "list files", Syntax: "ls [path]",
+		Examples: []Example{
+			{Code: "ls", Description: "List the current directory", Output: "a.txt\nb.txt\n"},
+			{Code: "seq 10", Description: "Count", Output: strings.Join(long, "\n")},
+			{Code: "ls missing", Description: "A missing file", Output: "ls: missing: No such file or directory", ExitCode: 2},
+			{Code: "false", Description: "Fail quietly", ExitCode: 1},
+		},
+	}}}
+}
+
+func TestRenderExampleOutput(t *testing.T) {
+	cmd := outputSheet().Commands[0]
+	detail := ansi.Strip(RenderCommandDetail(cmd, false))
+	for _, want := range []string{
+		"▾ Output\n    │ a.txt\n    │ b.txt\n",
+		"▸ Output\n    │ line 1\n",
+		"│ line 6\n    │ … 4 lines hidden (O to expand)\n",
+		"▾ Output (exit 2)\n    │ ls: missing: No such file or directory\n",
+		"Output (exit 1)\n",
+	} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected %q in:\n%s", want, detail)
+		}
+	}
+	if strings.Contains(detail, "line 7") {
+		t.Errorf("expected the long output cut short, got:\n%s", detail)
+	}
+
+	expanded := ansi.Strip(RenderCommandDetail(cmd, true))
+	if !strings.Contains(expanded, "▾ Output\n    │ line 1\n") || !strings.Contains(expanded, "│ line 10\n") || strings.Contains(expanded, "hidden") {
+		t.Errorf("expected the whole output when expanded, got:\n%s", expanded)
+	}
+}
+
+func TestExpandOutputKey(t *testing.T) {
+	m := newListModel(1, 100, 60)
+	m.setCheatSheet(outputSheet())
+	m = press(m, runes("O")) // nothing to expand in the list
+	if m.expandOutput {
+		t.Fatal("expected O to do nothing in the list")
+	}
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.truncatesOutput() || !strings.Contains(m.helpView(), "O: Expand output") {
+		t.Fatalf("expected the help to offer expanding, got %q", m.helpView())
+	}
+	m = press(m, runes("O"))
+	if !m.expandOutput || !strings.Contains(ansi.Strip(m.viewport.View()), "line 10") {
+		t.Errorf("expected O to expand the output")
+	}
+	m = press(m, runes("O"))
+	if m.expandOutput {
+		t.Errorf("expected O again to collapse the output")
+	}
+}
+
+func TestMarkdownExampleOutput(t *testing.T) {
+	sheet := outputSheet()
+	data, err := encodeMarkdownSheets([]CheatSheet{sheet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"```output\na.txt\nb.txt\n\n```", "```output exitCode=2\nls: missing", "```output exitCode=1\n\n```"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in:\n%s", want, data)
+		}
+	}
+	sheets, err := decodeMarkdownSheets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sheets[0].Commands[0].Examples, sheet.Commands[0].Examples) {
+		t.Errorf("round trip changed the examples:\n got %+v\nwant %+v", sheets[0].Commands[0].Examples, sheet.Commands[0].Examples)
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	data, err := exportHTML([]CheatSheet{outputSheet()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<title>Shell</title>",
+		"<h2>ls</h2>",
+		"<details class=\"output\" open>\n<summary>Output</summary>\n<pre><samp>a.txt\nb.txt\n</samp></pre>",
+		"<details class=\"output\">\n<summary>Output</summary>\n<pre><samp>line 1\n",
+		"<summary>Output (exit 2)</summary>",
+		"<summary>Output (exit 1)</summary>\n</details>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected %q in:\n%s", want, page)
+		}
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := runExport([]string{"pdf"}, &stdout, &stderr); status != 2 || !strings.Contains(stderr.String(), "targets: anki, html") {
+		t.Errorf("expected a usage error listing the targets, got %d: %s", status, stderr.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "git.html")
+	stderr.Reset()
+	if status := runExport([]string{"html", "-o", out, "cheatsheets/git.yaml"}, &stdout, &stderr); status != 0 {
+		t.Fatalf("export failed with %d: %s", status, stderr.String())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<samp>M  README.md\n M main.go\n?? notes.txt\n</samp>") {
+		t.Errorf("expected the output of git status -s in the page")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"strings"
+)
+
+// tagMatchMode decides how the included tags of a tagFilter combine
+type tagMatchMode int
+
+const (
+	matchAny tagMatchMode = iota // a command needs at least one included tag
+	matchAll                     // a command needs every included tag
+)
+
+// tagState is how a single tag takes part in a tagFilter
+type tagState int
+
+const (
+	tagNeutral tagState = iota
+	tagIncluded
+	tagExcluded
+)
+
+// tagFilter selects commands by the tags toggled in the tag bar: commands must
+// carry any (or all) of the included tags and none of the excluded ones.
+// Its methods return updated copies so models can share filters safely.
+type tagFilter struct {
+	include []string
+	exclude []string
+	mode    tagMatchMode
+}
+
+// Empty reports whether no tag has been included or excluded
+func (f tagFilter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// Matches reports whether a command with the given tags passes the filter
+func (f tagFilter) Matches(tags []string) bool {
+	for _, t := range f.exclude {
+		if containsString(tags, t) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+
+	if f.mode == matchAll {
+		for _, t := range f.include {
+			if !containsString(tags, t) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, t := range f.include {
+		if containsString(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns whether tag is included, excluded or not part of the filter
+func (f tagFilter) State(tag string) tagState {
+	switch {
+	case containsString(f.include, tag):
+		return tagIncluded
+	case containsString(f.exclude, tag):
+		return tagExcluded
+	default:
+		return tagNeutral
+	}
+}
+
+// Toggle includes tag, or drops it again when it was already included
+func (f tagFilter) Toggle(tag string) tagFilter {
+	included := containsString(f.include, tag)
+	f.include = removeString(f.include, tag)
+	f.exclude = removeString(f.exclude, tag)
+	if !included {
+		f.include = append(f.include, tag)
+	}
+	return f
+}
+
+// ToggleExclude excludes tag, or drops it again when it was already excluded
+func (f tagFilter) ToggleExclude(tag string) tagFilter {
+	excluded := containsString(f.exclude, tag)
+	f.include = removeString(f.include, tag)
+	f.exclude = removeString(f.exclude, tag)
+	if !excluded {
+		f.exclude = append(f.exclude, tag)
+	}
+	return f
+}
+
+// ToggleMode switches between matching any and all of the included tags
+func (f tagFilter) ToggleMode() tagFilter {
+	if f.mode == matchAll {
+		f.mode = matchAny
+	} else {
+		f.mode = matchAll
+	}
+	return f
+}
+
+// String renders the filter as an expression such as
+// "(git OR docker) AND NOT advanced"
+func (f tagFilter) String() string {
+	if f.Empty() {
+		return "all"
+	}
+
+	op := " OR "
+	if f.mode == matchAll {
+		op = " AND "
+	}
+
+	var terms []string
+	if len(f.include) > 0 {
+		expr := strings.Join(f.include, op)
+		if len(f.include) > 1 && len(f.exclude) > 0 && f.mode == matchAny {
+			expr = "(" + expr + ")"
+		}
+		terms = append(terms, expr)
+	}
+	for _, t := range f.exclude {
+		terms = append(terms, "NOT "+t)
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// commandFilter bundles everything besides text search that narrows the
+// command list. The zero value lets every command through.
+type commandFilter struct {
+	tags       tagFilter
+	complexity complexityFilter
+	platform   string            // when set, commands for other platforms are hidden
+	versions   map[string]string // when set, installed tool versions to hide commands outside their range
+	available  bool              // hide commands whose programs aren't on $PATH
+}
+
+// Matches reports whether cmd passes every part of the filter
+func (f commandFilter) Matches(cmd Command) bool {
+	if f.platform != "" && !matchesPlatform(cmd, f.platform) {
+		return false
+	}
+	if f.versions != nil && !matchesVersion(cmd, f.versions[commandTool(cmd)]) {
+		return false
+	}
+	if f.available && len(cmd.Missing) > 0 {
+		return false
+	}
+	return f.tags.Matches(commandTags(cmd)) && f.complexity.Matches(cmd.Complexity)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns a copy of list without s
+func removeString(list []string, s string) []string {
+	var out []string
+	for _, item := range list {
+		if item != s {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTagFilterMatches(t *testing.T) {
+	f := tagFilter{}.Toggle("git").Toggle("docker")
+
+	tests := []struct {
+		tags []string
+		mode tagMatchMode
+		want bool
+	}{
+		{[]string{"git"}, matchAny, true},
+		{[]string{"docker", "git"}, matchAny, true},
+		{[]string{"kubectl"}, matchAny, false},
+		{[]string{"git"}, matchAll, false},
+		{[]string{"docker", "git"}, matchAll, true},
+	}
+	for _, tt := range tests {
+		f.mode = tt.mode
+		if got := f.Matches(tt.tags); got != tt.want {
+			t.Errorf("%s: Matches(%v) = %v, want %v", f, tt.tags, got, tt.want)
+		}
+	}
+
+	// Exclusion wins over inclusion
+	f = tagFilter{}.Toggle("troubleshooting").ToggleExclude("advanced")
+	if !f.Matches([]string{"troubleshooting"}) {
+		t.Errorf("Expected troubleshooting to match %s", f)
+	}
+	if f.Matches([]string{"troubleshooting", "advanced"}) {
+		t.Errorf("Expected advanced commands to be excluded by %s", f)
+	}
+	if (tagFilter{}).ToggleExclude("advanced").Matches([]string{"advanced"}) {
+		t.Errorf("Expected exclusion alone to drop advanced commands")
+	}
+}
+
+func TestTagFilterToggles(t *testing.T) {
+	f := tagFilter{}.Toggle("git")
+	if f.State("git") != tagIncluded {
+		t.Fatalf("Expected git to be included")
+	}
+	f = f.ToggleExclude("git")
+	if f.State("git") != tagExcluded || len(f.include) != 0 {
+		t.Errorf("Expected excluding git to move it from include to exclude, got %+v", f)
+	}
+	f = f.ToggleExclude("git")
+	if !f.Empty() {
+		t.Errorf("Expected a second exclude toggle to clear git, got %+v", f)
+	}
+}
+
+func TestTagFilterString(t *testing.T) {
+	tests := []struct {
+		f    tagFilter
+		want string
+	}{
+		{tagFilter{}, "all"},
+		{tagFilter{include: []string{"git"}}, "git"},
+		{tagFilter{include: []string{"git", "docker"}}, "git OR docker"},
+		{tagFilter{include: []string{"git", "docker"}, mode: matchAll}, "git AND docker"},
+		{tagFilter{include: []string{"git", "docker"}, exclude: []string{"advanced"}}, "(git OR docker) AND NOT advanced"},
+		{tagFilter{exclude: []string{"advanced"}}, "NOT advanced"},
+	}
+	for _, tt := range tests {
+		if got := tt.f.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestTagFilterCombinesWithSearch(t *testing.T) {
+	m := newListModel(10, 100, 40) // cmd0..cmd9 tagged even/odd
+
+	// Toggle "odd" (index 2 in all, even, odd) and exclude nothing
+	m = press(m, tea.KeyMsg{Type: tea.KeyRight})
+	m = press(m, tea.KeyMsg{Type: tea.KeyRight})
+	m = press(m, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := m.activeTagFilter().String(); got != "odd" {
+		t.Fatalf("Expected filter 'odd', got %q", got)
+	}
+	if len(m.commands) != 5 {
+		t.Fatalf("Expected 5 odd commands, got %d", len(m.commands))
+	}
+
+	// Searching keeps the tag filter
+	m = press(m, runes("/"))
+	m = press(m, runes("cmd1"))
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.commands) != 1 || m.commands[0].Name != "cmd1" {
+		t.Fatalf("Expected only cmd1, got %v", m.commands)
+	}
+
+	// Tags still work while the search is applied
+	m = press(m, tea.KeyMsg{Type: tea.KeyLeft})
+	m = press(m, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = press(m, runes("m"))
+	if got := m.activeTagFilter().String(); got != "odd AND even" {
+		t.Fatalf("Expected 'odd AND even', got %q", got)
+	}
+	if len(m.commands) != 0 {
+		t.Errorf("Expected no command tagged both odd and even, got %d", len(m.commands))
+	}
+
+	// Esc clears the search first, then the tags
+	m = press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.searchActive || len(m.commands) != 0 {
+		t.Errorf("Expected the search cleared with tags still applied, got %d commands", len(m.commands))
+	}
+	m = press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.tagFilter.Empty() {
+		t.Errorf("Expected the tag filter cleared, got %s", m.tagFilter)
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSuggestions is how many completions a picker field offers at a time
+const maxSuggestions = 5
+
+// formFieldKind is what a row of the command form edits
+type formFieldKind int
+
+const (
+	fieldText       formFieldKind = iota // free text
+	fieldComplexity                      // one of the complexity levels
+	fieldPicker                          // a list of tags or command names, with completion
+	fieldAdd                             // adds an example, note or option row
+)
+
+// formField is one row of the command form. Fields address the command being
+// edited by key and index rather than by pointer, so forms copy safely along
+// with the model.
+type formField struct {
+	kind  formFieldKind
+	label string
+	key   string // which part of the Command the row edits
+	index int    // which example, note or option the row edits
+}
+
+// commandForm edits every attribute of a command: text fields, repeatable
+// example, note and option rows, and pickers for tags and related commands
+type commandForm struct {
+	original string   // name of the command being edited, empty when adding
+	path     string   // sheet the command is saved to
+	cmd      Command  // the command as edited so far
+	cursor   int      // index into fields()
+	input    string   // text typed into the picker under the cursor
+	tags     []string // tags known from the sheet, offered by the tag picker
+	names    []string // command names of the sheet, offered by the related picker
+	err      error    // why the last save attempt failed
+}
+
+// newCommandForm opens a form on cmd, saved to the sheet at path, seeding the
+// pickers from the sheet's commands. An empty original adds cmd as a new
+// command.
+func newCommandForm(cmd Command, original, path string, commands []Command) commandForm {
+	f := commandForm{original: original, path: path, cmd: cloneCommand(cmd)}
+	for _, tag := range UniqueTags(commands) {
+		if tag != "all" {
+			f.tags = append(f.tags, tag)
+		}
+	}
+	for _, c := range commands {
+		if c.Name != original {
+			f.names = append(f.names, c.Name)
+		}
+	}
+	return f
+}
+
+// cloneCommand copies cmd deeply enough that the form can edit it without
+// touching the loaded sheet
+func cloneCommand(cmd Command) Command {
+	cmd.Tags = append([]string(nil), cmd.Tags...)
+	cmd.Examples = append([]Example(nil), cmd.Examples...)
+	cmd.Notes = append([]string(nil), cmd.Notes...)
+	cmd.Options = append([]Option(nil), cmd.Options...)
+	cmd.Related = append([]string(nil), cmd.Related...)
+	return cmd
+}
+
+// fields lays out the rows of the form for the command as it is now
+func (f commandForm) fields() []formField {
+	fields := []formField{
+		{kind: fieldText, label: "Name", key: "name"},
+		{kind: fieldText, label: "Short description", key: "shortDesc"},
+		{kind: fieldText, label: "Syntax", key: "syntax"},
+		{kind: fieldComplexity, label: "Complexity", key: "complexity"},
+		{kind: fieldPicker, label: "Tags", key: "tags"},
+	}
+	for i := range f.cmd.Examples {
+		fields = append(fields,
+			formField{kind: fieldText, label: fmt.Sprintf("Example %d code", i+1), key: "example.code", index: i},
+			formField{kind: fieldText, label: fmt.Sprintf("Example %d description", i+1), key: "example.description", index: i})
+	}
+	fields = append(fields, formField{kind: fieldAdd, label: "+ Add example", key: "examples"})
+	for i := range f.cmd.Notes {
+		fields = append(fields, formField{kind: fieldText, label: fmt.Sprintf("Note %d", i+1), key: "note", index: i})
+	}
+	fields = append(fields, formField{kind: fieldAdd, label: "+ Add note", key: "notes"})
+	for i := range f.cmd.Options {
+		fields = append(fields,
+			formField{kind: fieldText, label: fmt.Sprintf("Option %d flag", i+1), key: "option.flag", index: i},
+			formField{kind: fieldText, label: fmt.Sprintf("Option %d description", i+1), key: "option.description", index: i})
+	}
+	fields = append(fields, formField{kind: fieldAdd, label: "+ Add option", key: "options"})
+	fields = append(fields, formField{kind: fieldPicker, label: "Related", key: "related"})
+	return fields
+}
+
+// current returns the field under the cursor
+func (f commandForm) current() formField {
+	return f.fields()[f.cursor]
+}
+
+// text returns the value of a text field
+func (f commandForm) text(field formField) string {
+	switch field.key {
+	case "name":
+		return f.cmd.Name
+	case "shortDesc":
+		return f.cmd.ShortDesc
+	case "syntax":
+		return f.cmd.Syntax
+	case "complexity":
+		return f.cmd.Complexity
+	case "example.code":
+		return f.cmd.Examples[field.index].Code
+	case "example.description":
+		return f.cmd.Examples[field.index].Description
+	case "note":
+		return f.cmd.Notes[field.index]
+	case "option.flag":
+		return f.cmd.Options[field.index].Flag
+	case "option.description":
+		return f.cmd.Options[field.index].Description
+	}
+	return ""
+}
+
+// setText stores the value of a text field
+func (f *commandForm) setText(field formField, s string) {
+	switch field.key {
+	case "name":
+		f.cmd.Name = s
+	case "shortDesc":
+		f.cmd.ShortDesc = s
+	case "syntax":
+		f.cmd.Syntax = s
+	case "complexity":
+		f.cmd.Complexity = s
+	case "example.code":
+		f.cmd.Examples[field.index].Code = s
+	case "example.description":
+		f.cmd.Examples[field.index].Description = s
+	case "note":
+		f.cmd.Notes[field.index] = s
+	case "option.flag":
+		f.cmd.Options[field.index].Flag = s
+	case "option.description":
+		f.cmd.Options[field.index].Description = s
+	}
+}
+
+// picked returns the items chosen in a picker field
+func (f commandForm) picked(field formField) []string {
+	if field.key == "tags" {
+		return f.cmd.Tags
+	}
+	return f.cmd.Related
+}
+
+func (f *commandForm) setPicked(field formField, items []string) {
+	if field.key == "tags" {
+		f.cmd.Tags = items
+	} else {
+		f.cmd.Related = items
+	}
+}
+
+// suggestions returns the choices of the picker under the cursor that start
+// with the typed text and haven't been picked yet
+func (f commandForm) suggestions() []string {
+	field := f.current()
+	if field.kind != fieldPicker {
+		return nil
+	}
+	choices := f.tags
+	if field.key == "related" {
+		choices = f.names
+	}
+
+	var out []string
+	prefix := strings.ToLower(f.input)
+	for _, choice := range choices {
+		if strings.HasPrefix(strings.ToLower(choice), prefix) && !containsString(f.picked(field), choice) {
+			out = append(out, choice)
+			if len(out) == maxSuggestions {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// pick adds item to the picker under the cursor
+func (f *commandForm) pick(item string) {
+	item = strings.TrimSpace(item)
+	field := f.current()
+	if item != "" && !containsString(f.picked(field), item) {
+		f.setPicked(field, append(f.picked(field), item))
+	}
+	f.input = ""
+}
+
+// moveCursor moves to the field delta rows away, keeping within the form
+func (f *commandForm) moveCursor(delta int) {
+	f.cursor = max(0, min(f.cursor+delta, len(f.fields())-1))
+	f.input = ""
+}
+
+// addRow appends an example, note or option and moves to its first field
+func (f *commandForm) addRow(key string) {
+	switch key {
+	case "examples":
+		f.cmd.Examples = append(f.cmd.Examples, Example{})
+	case "notes":
+		f.cmd.Notes = append(f.cmd.Notes, "")
+	case "options":
+		f.cmd.Options = append(f.cmd.Options, Option{})
+	}
+	// The new row takes the place of the add button
+	for i, field := range f.fields() {
+		if i >= f.cursor && field.kind == fieldText {
+			f.cursor = i
+			break
+		}
+	}
+}
+
+// removeRow deletes the example, note or option under the cursor
+func (f *commandForm) removeRow() {
+	field := f.current()
+	switch field.key {
+	case "example.code", "example.description":
+		f.cmd.Examples = append(f.cmd.Examples[:field.index], f.cmd.Examples[field.index+1:]...)
+	case "note":
+		f.cmd.Notes = append(f.cmd.Notes[:field.index], f.cmd.Notes[field.index+1:]...)
+	case "option.flag", "option.description":
+		f.cmd.Options = append(f.cmd.Options[:field.index], f.cmd.Options[field.index+1:]...)
+	default:
+		return
+	}
+	f.cursor = min(f.cursor, len(f.fields())-1)
+}
+
+// cycleComplexity steps the complexity field through unset and each level
+func (f *commandForm) cycleComplexity(delta int) {
+	levels := len(complexityNames)
+	level, _ := parseComplexity(f.cmd.Complexity)
+	level = complexityLevel((int(level) + delta + levels) % levels)
+	if level == complexityUnset {
+		f.cmd.Complexity = ""
+	} else {
+		f.cmd.Complexity = level.String()
+	}
+}
+
+// command returns the edited command with blank rows left out
+func (f commandForm) command() Command {
+	cmd := cloneCommand(f.cmd)
+	cmd.Name = strings.TrimSpace(cmd.Name)
+	cmd.Examples = cmd.Examples[:0]
+	for _, ex := range f.cmd.Examples {
+		if strings.TrimSpace(ex.Code) != "" || strings.TrimSpace(ex.Description) != "" {
+			cmd.Examples = append(cmd.Examples, ex)
+		}
+	}
+	cmd.Notes = cmd.Notes[:0]
+	for _, note := range f.cmd.Notes {
+		if strings.TrimSpace(note) != "" {
+			cmd.Notes = append(cmd.Notes, note)
+		}
+	}
+	cmd.Options = cmd.Options[:0]
+	for _, opt := range f.cmd.Options {
+		if strings.TrimSpace(opt.Flag) != "" || strings.TrimSpace(opt.Description) != "" {
+			cmd.Options = append(cmd.Options, opt)
+		}
+	}
+	return cmd
+}
+
+// validate checks the edited command can be saved to a sheet holding the
+// other commands in names
+func (f commandForm) validate() error {
+	cmd := f.command()
+	if cmd.Name == "" {
+		return errors.New("a command needs a name")
+	}
+	if containsString(f.names, cmd.Name) {
+		return fmt.Errorf("the sheet already has a command called %q", cmd.Name)
+	}
+	return nil
+}
+
+// saveCommandForm writes the form's command to its sheet, replacing the
+// command it was opened on
+func saveCommandForm(f commandForm) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	doc, err := loadSheetDocument(f.path)
+	if err != nil {
+		return err
+	}
+	if err := doc.SetCommand(f.original, f.command()); err != nil {
+		return err
+	}
+	return doc.Save(f.path)
+}
+
+// openForm shows the command form on cmd; an empty original adds a command.
+// Like editSelected, a command the sheet inherits or includes is saved to the
+// sheet it comes from.
+func (m *model) openForm(cmd Command, original string) {
+	path := m.cheatSheet.Path
+	if cmd.Source != "" {
+		path = cmd.Source
+	}
+	form := newCommandForm(cmd, original, path, m.cheatSheet.Commands)
+	m.form = &form
+}
+
+// updateForm handles key presses while the command form is shown
+func (m model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := *m.form
+	field := f.current()
+
+	switch {
+	case msg.Type == tea.KeyCtrlS:
+		if err := saveCommandForm(f); err != nil {
+			f.err = err
+			break
+		}
+		m.form = nil
+		m.editPath = f.path
+		m.reloadSheet(m.cheatSheet.Path, f.command().Name)
+		return m, nil
+	case key.Matches(msg, keys.Back):
+		// Discard the form
+		m.form = nil
+		return m, nil
+	case msg.Type == tea.KeyCtrlD:
+		f.removeRow()
+	case msg.Type == tea.KeyTab && field.kind == fieldPicker && f.input != "":
+		// Complete the typed text to the first suggestion
+		if suggestions := f.suggestions(); len(suggestions) > 0 {
+			f.pick(suggestions[0])
+		}
+	// Arrows rather than the j/k bindings move between fields, so the letters
+	// can be typed
+	case msg.Type == tea.KeyTab || msg.Type == tea.KeyDown:
+		f.moveCursor(1)
+	case msg.Type == tea.KeyShiftTab || msg.Type == tea.KeyUp:
+		f.moveCursor(-1)
+	case msg.Type == tea.KeyEnter:
+		switch {
+		case field.kind == fieldAdd:
+			f.addRow(field.key)
+		case field.kind == fieldPicker && f.input != "":
+			f.pick(f.input)
+		default:
+			f.moveCursor(1)
+		}
+	case field.kind == fieldComplexity && (msg.Type == tea.KeyLeft || msg.Type == tea.KeyRight || msg.Type == tea.KeySpace):
+		if msg.Type == tea.KeyLeft {
+			f.cycleComplexity(-1)
+		} else {
+			f.cycleComplexity(1)
+		}
+	case msg.Type == tea.KeyBackspace:
+		switch field.kind {
+		case fieldText:
+			if s := f.text(field); s != "" {
+				r := []rune(s)
+				f.setText(field, string(r[:len(r)-1]))
+			}
+		case fieldPicker:
+			if f.input != "" {
+				r := []rune(f.input)
+				f.input = string(r[:len(r)-1])
+			} else if items := f.picked(field); len(items) > 0 {
+				// Drop the last picked item
+				f.setPicked(field, items[:len(items)-1])
+			}
+		}
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+		typed := string(msg.Runes)
+		if msg.Type == tea.KeySpace {
+			typed = " "
+		}
+		switch field.kind {
+		case fieldText:
+			f.setText(field, f.text(field)+typed)
+		case fieldPicker:
+			if field.key == "tags" && typed == "," {
+				f.pick(f.input)
+			} else {
+				f.input += typed
+			}
+		}
+	}
+
+	m.form = &f
+	return m, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeText sends s to the model one key at a time
+func typeText(m model, s string) model {
+	for _, r := range s {
+		if r == ' ' {
+			m = press(m, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+		} else {
+			m = press(m, runes(string(r)))
+		}
+	}
+	return m
+}
+
+func TestCommandFormRows(t *testing.T) {
+	f := newCommandForm(Command{}, "", "", nil)
+	f.cursor = 5 // + Add example
+	f.addRow(f.current().key)
+	if len(f.cmd.Examples) != 1 || f.current().key != "example.code" {
+		t.Fatalf("Expected the cursor on the new example, got %+v", f.current())
+	}
+	f.setText(f.current(), "ls")
+
+	f.removeRow()
+	if len(f.cmd.Examples) != 0 {
+		t.Errorf("Expected the example removed, got %+v", f.cmd.Examples)
+	}
+
+	f.cmd.Notes = []string{"", "kept"}
+	if got := f.command().Notes; !reflect.DeepEqual(got, []string{"kept"}) {
+		t.Errorf("Expected blank notes dropped, got %q", got)
+	}
+}
+
+func TestCommandFormPickers(t *testing.T) {
+	commands := []Command{
+		{Name: "git status", Tags: []string{"basics", "branching"}},
+		{Name: "git stash", Tags: []string{"basics"}},
+	}
+	f := newCommandForm(Command{}, "", "", commands)
+	f.cursor = 4 // Tags
+
+	f.input = "b"
+	if got := f.suggestions(); !reflect.DeepEqual(got, []string{"basics", "branching"}) {
+		t.Errorf("Expected both b tags suggested, got %q", got)
+	}
+	f.pick("basics")
+	f.input = "b"
+	if got := f.suggestions(); !reflect.DeepEqual(got, []string{"branching"}) {
+		t.Errorf("Expected picked tags left out, got %q", got)
+	}
+
+	f.cursor = len(f.fields()) - 1 // Related
+	f.input = "git sta"
+	if got := f.suggestions(); !reflect.DeepEqual(got, []string{"git status", "git stash"}) {
+		t.Errorf("Expected command names suggested, got %q", got)
+	}
+
+	f.cmd.Name = "git status"
+	if err := f.validate(); err == nil {
+		t.Error("Expected a duplicate name to be refused")
+	}
+}
+
+func TestCommandFormSave(t *testing.T) {
+	m, path := editedModel(t, "second")
+
+	m = press(m, runes("a"))
+	if m.form == nil {
+		t.Fatal("Expected a to open the form")
+	}
+
+	// Name, then Tab over the description and syntax to the complexity
+	m = typeText(m, "fourth jk")
+	m = press(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = typeText(m, "The fourth command")
+	m = press(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = press(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = press(m, tea.KeyMsg{Type: tea.KeyRight})
+
+	// Complete a tag from the sheet's tags
+	m = press(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = typeText(m, "b")
+	m = press(m, tea.KeyMsg{Type: tea.KeyTab})
+	if got := m.form.cmd.Tags; !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("Expected the tag completed, got %q", got)
+	}
+	if !strings.Contains(m.View(), "Add command") {
+		t.Error("Expected the form to be shown")
+	}
+
+	m = press(m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	if m.form != nil {
+		t.Fatalf("Expected the form closed after saving, got error %v", m.form.err)
+	}
+	got := m.commands[m.currentCommand]
+	if got.Name != "fourth jk" || got.Complexity != "beginner" || got.ShortDesc != "The fourth command" {
+		t.Errorf("Expected the new command selected, got %+v", got)
+	}
+
+	sheet, err := LoadCheatSheet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Commands) != 4 {
+		t.Errorf("Expected the command saved to the file, got %d commands", len(sheet.Commands))
+	}
+}
+
+func TestCommandFormSaveInherited(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"main.yaml": "title: Main\nextends: base.yaml\ncommands:\n  - name: own\n",
+		"base.yaml": "title: Base\ncommands:\n  - name: shared\n    shortDesc: Shared\n",
+	})
+	path, base := filepath.Join(dir, "main.yaml"), filepath.Join(dir, "base.yaml")
+	m := initialModel(path, dir)
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(loadCheatSheetMsg(path, 0))
+	m = tm.(model)
+	for i, cmd := range m.commands {
+		if cmd.Name == "shared" {
+			m.selectCommand(i)
+		}
+	}
+
+	m = press(m, runes("f"))
+	if m.form == nil || m.form.path != base {
+		t.Fatalf("Expected the form to save to %s, got %+v", base, m.form)
+	}
+	if !strings.Contains(m.View(), "Edit shared in base.yaml") {
+		t.Errorf("Expected the form to name the sheet it saves to, got:\n%s", m.View())
+	}
+	m = press(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = typeText(m, " by all")
+	m = press(m, tea.KeyMsg{Type: tea.KeyCtrlS})
+	if m.form != nil {
+		t.Fatalf("Expected the form closed after saving, got error %v", m.form.err)
+	}
+
+	sheet, err := LoadCheatSheet(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sheet.Commands[0].ShortDesc; got != "Shared by all" {
+		t.Errorf("Expected the command saved to the sheet it comes from, got %q", got)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "title: Main\nextends: base.yaml\ncommands:\n  - name: own\n" {
+		t.Errorf("Expected the extending sheet left alone, got:\n%s", data)
+	}
+	if got := m.commands[m.currentCommand]; got.Name != "shared" || got.ShortDesc != "Shared by all" || got.Source != base {
+		t.Errorf("Expected the saved command selected again, got %+v", got)
+	}
+}
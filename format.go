@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Key orders of the canonical layout, following the structs in parsing.go.
+// Keys the structs don't know about keep their order after these.
+var (
+	sheetFields   = []string{"title", "description", "category", "extends", "include", "drop", "vars", "commands"}
+	exampleFields = []string{"code", "description", "output", "exitCode", "verify"}
+	optionFields  = []string{"flag", "description"}
+)
+
+// formatSheet rewrites the text of a cheatsheet into the canonical layout:
+// keys in struct order, strings double quoted (block literals when they span
+// lines), tags sorted and deduplicated, tags and related commands as flow
+// lists, complexity in lower case and a blank line between commands.
+// Comments are kept. Each document of a multi-document file is formatted on
+// its own.
+func formatSheet(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		var root yaml.Node
+		err := dec.Decode(&root)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, documentError(i, err)
+		}
+		formatted, err := formatDocument(&root)
+		if err != nil {
+			return nil, documentError(i, err)
+		}
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(formatted)
+	}
+	if out.Len() == 0 {
+		return data, nil
+	}
+	return out.Bytes(), nil
+}
+
+// formatDocument formats a single YAML document
+func formatDocument(root *yaml.Node) ([]byte, error) {
+	if len(root.Content) == 0 || root.Content[0].Tag == "!!null" {
+		// Nothing to lay out but comments, which are kept as they are
+		return documentComments(root), nil
+	}
+	sheet := root.Content[0]
+	if sheet.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("a cheatsheet must be a mapping, got %s", nodeKindName(sheet))
+	}
+
+	// A comment heading the first key heads the document, whichever key
+	// ends up first
+	var header string
+	if len(sheet.Content) > 0 {
+		header, sheet.Content[0].HeadComment = sheet.Content[0].HeadComment, ""
+	}
+	orderKeys(sheet, sheetFields)
+	if len(sheet.Content) > 0 {
+		first := sheet.Content[0]
+		if header != "" && first.HeadComment != "" {
+			header += "\n"
+		}
+		first.HeadComment = header + first.HeadComment
+	}
+	if commands := mappingValue(sheet, "commands"); commands != nil && commands.Kind == yaml.SequenceNode {
+		commands.Style = 0
+		for _, cmd := range commands.Content {
+			formatCommand(cmd)
+		}
+	}
+	quoteStrings(sheet)
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return separateCommands(b.Bytes())
+}
+
+// documentComments returns the comments of a document without keys, a line
+// each, or nil when it has none
+func documentComments(root *yaml.Node) []byte {
+	comments := []string{root.HeadComment}
+	if len(root.Content) > 0 {
+		value := root.Content[0]
+		comments = append(comments, value.HeadComment, value.LineComment, value.FootComment)
+	}
+	comments = append(comments, root.FootComment)
+
+	var b bytes.Buffer
+	for _, comment := range comments {
+		if comment != "" {
+			b.WriteString(comment + "\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// formatCommand puts one command node into the canonical layout
+func formatCommand(cmd *yaml.Node) {
+	if cmd.Kind != yaml.MappingNode {
+		return
+	}
+	cmd.Style = 0
+	orderKeys(cmd, commandFields)
+
+	if tags := mappingValue(cmd, "tags"); tags != nil && tags.Kind == yaml.SequenceNode {
+		tags.Style = yaml.FlowStyle
+		seen := make(map[string]bool)
+		var unique []*yaml.Node
+		for _, tag := range tags.Content {
+			if !seen[tag.Value] {
+				seen[tag.Value] = true
+				unique = append(unique, tag)
+			}
+		}
+		sort.SliceStable(unique, func(i, j int) bool { return unique[i].Value < unique[j].Value })
+		tags.Content = unique
+	}
+	if related := mappingValue(cmd, "related"); related != nil && related.Kind == yaml.SequenceNode {
+		related.Style = yaml.FlowStyle
+	}
+	if complexity := mappingValue(cmd, "complexity"); complexity != nil {
+		if level, ok := parseComplexity(complexity.Value); ok {
+			complexity.Value = level.String()
+		}
+	}
+
+	for key, fields := range map[string][]string{"examples": exampleFields, "options": optionFields} {
+		list := mappingValue(cmd, key)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		list.Style = 0
+		for _, item := range list.Content {
+			if item.Kind == yaml.MappingNode {
+				item.Style = 0
+				orderKeys(item, fields)
+			}
+		}
+	}
+	if notes := mappingValue(cmd, "notes"); notes != nil && notes.Kind == yaml.SequenceNode {
+		notes.Style = 0
+	}
+}
+
+// orderKeys sorts the pairs of a mapping node into the order of fields,
+// leaving unknown keys after them in their original order
+func orderKeys(mapping *yaml.Node, fields []string) {
+	type pair struct{ key, value *yaml.Node }
+	var pairs []pair
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, pair{mapping.Content[i], mapping.Content[i+1]})
+	}
+	rank := func(p pair) int {
+		for i, field := range fields {
+			if p.key.Value == field {
+				return i
+			}
+		}
+		return len(fields)
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return rank(pairs[i]) < rank(pairs[j]) })
+
+	mapping.Content = mapping.Content[:0]
+	for _, p := range pairs {
+		mapping.Content = append(mapping.Content, p.key, p.value)
+	}
+}
+
+// quoteStrings double quotes every string value under n, using literal blocks
+// for strings that span lines, and leaves keys plain
+func quoteStrings(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			n.Content[i].Style = 0
+			quoteStrings(n.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for _, child := range n.Content {
+			quoteStrings(child)
+		}
+	case yaml.ScalarNode:
+		if n.Tag != "!!str" {
+			return
+		}
+		if strings.Contains(strings.TrimRight(n.Value, "\n"), "\n") {
+			n.Style = yaml.LiteralStyle
+		} else {
+			n.Style = yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+// separateCommands puts a blank line in front of every command but the
+// first, above any comments that head it
+func separateCommands(data []byte) ([]byte, error) {
+	doc, err := parseSheetDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	commands := doc.commands()
+	if commands == nil || commands.Kind != yaml.SequenceNode {
+		return data, nil
+	}
+
+	blank := make(map[int]bool)
+	for i := 1; i < len(commands.Content); i++ {
+		at := commands.Content[i].Line - 1
+		indent := doc.dashIndent(i)
+		for at > 0 {
+			line := doc.lines[at-1]
+			if !strings.HasPrefix(strings.TrimSpace(line), "#") || len(line)-len(strings.TrimLeft(line, " ")) > indent {
+				break
+			}
+			at--
+		}
+		blank[at] = true
+	}
+
+	var b bytes.Buffer
+	for i, line := range doc.lines {
+		if blank[i] && (i == 0 || strings.TrimSpace(doc.lines[i-1]) != "") {
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+	}
+	return b.Bytes(), nil
+}
+
+// runFmt implements "cheatcheat fmt": it prints the canonical layout of each
+// sheet, rewrites the sheets with -w, or with --check lists the sheets that
+// aren't formatted and fails
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result back to the sheets instead of printing it")
+	check := flags.Bool("check", false, "list sheets that aren't formatted and exit non-zero if there are any")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat fmt [-w] [--check] paths...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (*write && *check) {
+		flags.Usage()
+		return 2
+	}
+
+	paths, err := sheetPaths(flags.Args(), "yaml")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range paths {
+		if format, _ := formatForPath(path); format.name != "yaml" {
+			fmt.Fprintf(stderr, "%s: fmt only formats YAML sheets, not %s\n", path, format.name)
+			status = 1
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 1
+			continue
+		}
+		formatted, err := formatSheet(data)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+
+		switch {
+		case *check:
+			if !bytes.Equal(data, formatted) {
+				fmt.Fprintln(stdout, path)
+				status = 1
+			}
+		case *write:
+			if !bytes.Equal(data, formatted) {
+				if err := os.WriteFile(path, formatted, 0644); err != nil {
+					fmt.Fprintln(stderr, err)
+					status = 1
+				}
+			}
+		default:
+			stdout.Write(formatted)
+		}
+	}
+	return status
+}
+
+// sheetPaths expands the paths given on the command line: files are taken as
+// they are, directories stand for every cheatsheet below them, or for those
+// in the named formats only
+func sheetPaths(args []string, formats ...string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		sheets, err := DiscoverCheatsheets(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, sheet := range sheets {
+			if format, _ := formatForPath(sheet); len(formats) > 0 && !containsString(formats, format.name) {
+				continue
+			}
+			paths = append(paths, filepath.Join(arg, sheet))
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const messySheet = `commands:
+- syntax: tar -xf <archive>
+  name: tar extract   # keep me
+  tags:
+  - extract
+  - archive
+  - extract
+  complexity: Beginner
+  examples:
+  - description: Extract here
+    code: 'tar -xf a.tar'
+# SECOND
+- name: zip
+  notes: ["one", "two"]
+  related:
+  - unzip
+  shortDesc: |
+    Spans
+    two lines
+title: Archives
+`
+
+const canonicalSheet = `title: "Archives"
+commands:
+  - name: "tar extract" # keep me
+    syntax: "tar -xf <archive>"
+    tags: ["archive", "extract"]
+    complexity: "beginner"
+    examples:
+      - code: "tar -xf a.tar"
+        description: "Extract here"
+
+  # SECOND
+  - name: "zip"
+    shortDesc: |
+      Spans
+      two lines
+    notes:
+      - "one"
+      - "two"
+    related: ["unzip"]
+`
+
+func TestFormatSheet(t *testing.T) {
+	got, err := formatSheet([]byte(messySheet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != canonicalSheet {
+		t.Errorf("Unexpected layout:\n%s", got)
+	}
+
+	again, err := formatSheet(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, got) {
+		t.Errorf("Expected formatting to be idempotent, got:\n%s", again)
+	}
+}
+
+func TestFormatSheetComments(t *testing.T) {
+	tests := []struct {
+		sheet, want string
+	}{
+		{"# Nothing here yet\n# but comments\n", "# Nothing here yet\n# but comments\n"},
+		{"# Header only\n---\n", "# Header only\n"},
+		{"title: a\n---\n# Another sheet, one day\n", "title: \"a\"\n\n# Another sheet, one day\n---\n"},
+		{"# header comment\ncommands: []\ntitle: a\n", "# header comment\ntitle: \"a\"\ncommands: []\n"},
+	}
+	for _, tt := range tests {
+		got, err := formatSheet([]byte(tt.sheet))
+		if err != nil {
+			t.Errorf("%q: %v", tt.sheet, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("formatSheet(%q) = %q, want %q", tt.sheet, got, tt.want)
+		}
+		if again, _ := formatSheet(got); !bytes.Equal(again, got) {
+			t.Errorf("Expected formatting %q to be idempotent, got %q", got, again)
+		}
+	}
+}
+
+func TestBundledSheetsFormatted(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := runFmt([]string{"--check", "cheatsheets"}, &stdout, &stderr); status != 0 {
+		t.Errorf("Expected the bundled sheets to be formatted, fmt --check listed:\n%s%s", stdout.String(), stderr.String())
+	}
+}
+
+func TestRunFmt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messy.yaml")
+	if err := os.WriteFile(path, []byte(messySheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := runFmt([]string{path}, &stdout, &stderr); status != 0 || stdout.String() != canonicalSheet {
+		t.Errorf("Expected the canonical sheet printed, got status %d:\n%s", status, stdout.String())
+	}
+
+	stdout.Reset()
+	if status := runFmt([]string{"--check", path}, &stdout, &stderr); status != 1 || strings.TrimSpace(stdout.String()) != path {
+		t.Errorf("Expected --check to fail and list %s, got status %d: %q", path, status, stdout.String())
+	}
+
+	if status := runFmt([]string{"-w", path}, &stdout, &stderr); status != 0 {
+		t.Fatalf("Expected -w to succeed, got status %d: %s", status, stderr.String())
+	}
+	stdout.Reset()
+	if status := runFmt([]string{"--check", path}, &stdout, &stderr); status != 0 {
+		t.Errorf("Expected the rewritten sheet to pass --check, got status %d: %q", status, stdout.String())
+	}
+
+	if status := runFmt([]string{"-w", "--check", path}, &stdout, &stderr); status != 2 {
+		t.Errorf("Expected -w with --check to be a usage error, got status %d", status)
+	}
+}
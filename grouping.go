@@ -0,0 +1,201 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// selectorGrouping is how the cheatsheet selector arranges its list
+type selectorGrouping int
+
+const (
+	groupByDirectory selectorGrouping = iota // collapsible directory tree
+	groupByCategory                          // one group per sheet category
+	groupNone                                // flat list of paths
+)
+
+var selectorGroupingNames = []string{"tree", "category", "flat"}
+
+// uncategorized is the group for sheets that don't declare a category
+const uncategorized = "Uncategorized"
+
+// parseSelectorGrouping maps a saved grouping name back to its value,
+// falling back to the directory tree
+func parseSelectorGrouping(s string) selectorGrouping {
+	for i, name := range selectorGroupingNames {
+		if s == name {
+			return selectorGrouping(i)
+		}
+	}
+	return groupByDirectory
+}
+
+func (g selectorGrouping) String() string {
+	return selectorGroupingNames[g]
+}
+
+// Next cycles through the groupings: tree, category, flat
+func (g selectorGrouping) Next() selectorGrouping {
+	return (g + 1) % selectorGrouping(len(selectorGroupingNames))
+}
+
+// selectorRow is one line of the cheatsheet selector: either a group header
+// or a sheet
+type selectorRow struct {
+	group     string      // key of the group this row heads, empty for sheets
+	label     string      // text shown for the row
+	depth     int         // indentation level
+	collapsed bool        // group rows only
+	members   []sheetInfo // group rows: every sheet inside the group
+	sheet     sheetInfo   // sheet rows only
+}
+
+func (r selectorRow) isGroup() bool {
+	return r.group != ""
+}
+
+// key identifies the row across rebuilds of the selector list
+func (r selectorRow) key() string {
+	if r.isGroup() {
+		return r.group
+	}
+	return "sheet:" + r.sheet.Name()
+}
+
+// buildSelectorRows arranges the sheets into selector rows. Collapsed groups
+// hide their contents unless expandAll is set, as it is while searching.
+func buildSelectorRows(infos []sheetInfo, grouping selectorGrouping, collapsed map[string]bool, expandAll bool) []selectorRow {
+	isCollapsed := func(key string) bool {
+		return !expandAll && collapsed[key]
+	}
+
+	switch grouping {
+	case groupByCategory:
+		return categoryRows(infos, isCollapsed)
+	case groupByDirectory:
+		root := newDirNode()
+		for _, info := range infos {
+			root.add(strings.Split(filepath.ToSlash(info.Path), "/"), info)
+		}
+		return root.rows("", 0, isCollapsed)
+	default:
+		rows := make([]selectorRow, 0, len(infos))
+		for _, info := range infos {
+			rows = append(rows, selectorRow{label: info.Name(), sheet: info})
+		}
+		return rows
+	}
+}
+
+// categoryRows groups the sheets by their Category, alphabetically, with
+// sheets lacking one gathered at the end
+func categoryRows(infos []sheetInfo, isCollapsed func(string) bool) []selectorRow {
+	groups := make(map[string][]sheetInfo)
+	for _, info := range infos {
+		category := strings.TrimSpace(info.Category)
+		if category == "" {
+			category = uncategorized
+		}
+		groups[category] = append(groups[category], info)
+	}
+
+	var categories []string
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if (categories[i] == uncategorized) != (categories[j] == uncategorized) {
+			return categories[j] == uncategorized
+		}
+		return strings.ToLower(categories[i]) < strings.ToLower(categories[j])
+	})
+
+	var rows []selectorRow
+	for _, category := range categories {
+		key := "category:" + category
+		members := groups[category]
+		rows = append(rows, selectorRow{
+			group:     key,
+			label:     category,
+			collapsed: isCollapsed(key),
+			members:   members,
+		})
+		if isCollapsed(key) {
+			continue
+		}
+		for _, info := range members {
+			rows = append(rows, selectorRow{label: info.Name(), depth: 1, sheet: info})
+		}
+	}
+	return rows
+}
+
+// dirNode is a directory of the cheatsheet tree
+type dirNode struct {
+	dirs   map[string]*dirNode
+	sheets []sheetInfo
+}
+
+func newDirNode() *dirNode {
+	return &dirNode{dirs: make(map[string]*dirNode)}
+}
+
+// add files info under the directory path given by parts, the last of which
+// is the file name
+func (n *dirNode) add(parts []string, info sheetInfo) {
+	if len(parts) == 1 {
+		n.sheets = append(n.sheets, info)
+		return
+	}
+	child, ok := n.dirs[parts[0]]
+	if !ok {
+		child = newDirNode()
+		n.dirs[parts[0]] = child
+	}
+	child.add(parts[1:], info)
+}
+
+// all returns every sheet in the directory and below it
+func (n *dirNode) all() []sheetInfo {
+	sheets := append([]sheetInfo(nil), n.sheets...)
+	for _, name := range n.dirNames() {
+		sheets = append(sheets, n.dirs[name].all()...)
+	}
+	return sheets
+}
+
+func (n *dirNode) dirNames() []string {
+	var names []string
+	for name := range n.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rows lists the directory's subdirectories, then its sheets, descending into
+// the subdirectories that aren't collapsed
+func (n *dirNode) rows(prefix string, depth int, isCollapsed func(string) bool) []selectorRow {
+	var rows []selectorRow
+	for _, name := range n.dirNames() {
+		child := n.dirs[name]
+		dirPath := path.Join(prefix, name)
+		key := "dir:" + dirPath
+		rows = append(rows, selectorRow{
+			group:     key,
+			label:     name + "/",
+			depth:     depth,
+			collapsed: isCollapsed(key),
+			members:   child.all(),
+		})
+		if !isCollapsed(key) {
+			rows = append(rows, child.rows(dirPath, depth+1, isCollapsed)...)
+		}
+	}
+	for _, info := range n.sheets {
+		rows = append(rows, selectorRow{label: path.Base(filepath.ToSlash(info.Name())), depth: depth, sheet: info})
+	}
+	return rows
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func groupingFixture() []sheetInfo {
+	return []sheetInfo{
+		{Path: "git.yaml", Title: "Git", Category: "VCS"},
+		{Path: "linux/files.yaml", Title: "Files", Category: "Linux"},
+		{Path: "linux/net/ip.yaml", Title: "IP"},
+		{Path: "databases/mysql.yaml", Title: "MySQL", Category: "Databases"},
+	}
+}
+
+func rowLabels(rows []selectorRow) []string {
+	var labels []string
+	for _, row := range rows {
+		labels = append(labels, row.label)
+	}
+	return labels
+}
+
+func TestBuildSelectorRowsTree(t *testing.T) {
+	rows := buildSelectorRows(groupingFixture(), groupByDirectory, nil, false)
+
+	want := []string{"databases/", "mysql.yaml", "linux/", "net/", "ip.yaml", "files.yaml", "git.yaml"}
+	if got := rowLabels(rows); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected rows %v, got %v", want, got)
+	}
+	if rows[2].group != "dir:linux" || len(rows[2].members) != 2 {
+		t.Errorf("Expected linux/ to hold 2 sheets, got %+v", rows[2])
+	}
+	if rows[4].depth != 2 {
+		t.Errorf("Expected ip.yaml at depth 2, got %d", rows[4].depth)
+	}
+}
+
+func TestBuildSelectorRowsCategory(t *testing.T) {
+	rows := buildSelectorRows(groupingFixture(), groupByCategory, nil, false)
+
+	want := []string{"Databases", "databases/mysql.yaml", "Linux", "linux/files.yaml", "VCS", "git.yaml", uncategorized, "linux/net/ip.yaml"}
+	if got := rowLabels(rows); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected rows %v, got %v", want, got)
+	}
+}
+
+func TestBuildSelectorRowsFlat(t *testing.T) {
+	rows := buildSelectorRows(groupingFixture(), groupNone, nil, false)
+	if len(rows) != 4 {
+		t.Fatalf("Expected 4 rows, got %d", len(rows))
+	}
+	for _, row := range rows {
+		if row.isGroup() {
+			t.Errorf("Expected no group rows in the flat view, got %q", row.label)
+		}
+	}
+}
+
+func TestBuildSelectorRowsCollapsed(t *testing.T) {
+	collapsed := map[string]bool{"dir:linux": true}
+
+	rows := buildSelectorRows(groupingFixture(), groupByDirectory, collapsed, false)
+	want := []string{"databases/", "mysql.yaml", "linux/", "git.yaml"}
+	if got := rowLabels(rows); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected rows %v, got %v", want, got)
+	}
+	if !rows[2].collapsed {
+		t.Error("Expected linux/ to be marked collapsed")
+	}
+
+	// Searching expands every group so matches aren't hidden
+	rows = buildSelectorRows(groupingFixture(), groupByDirectory, collapsed, true)
+	if len(rows) != 7 {
+		t.Errorf("Expected every row while expanded, got %v", rowLabels(rows))
+	}
+}
+
+func TestSelectorCollapseKeys(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	m := initialModelWithSelector("cheatsheets")
+	updated, _ := m.Update(loadCheatsheetsMsg("cheatsheets"))
+	m = updated.(model)
+
+	if !m.selectorRows[0].isGroup() {
+		t.Fatalf("Expected the tree view to start with a directory, got %q", m.selectorRows[0].label)
+	}
+	total := len(m.selectorRows)
+
+	// Enter on a group folds it, Right unfolds it again
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.selectorRows) >= total || !m.selectorRows[0].collapsed {
+		t.Fatalf("Expected the first group collapsed, got %d of %d rows", len(m.selectorRows), total)
+	}
+	m = press(m, tea.KeyMsg{Type: tea.KeyRight})
+	if len(m.selectorRows) != total {
+		t.Fatalf("Expected every row back after expanding, got %d of %d", len(m.selectorRows), total)
+	}
+
+	// Left on a sheet collapses its parent and moves the cursor there
+	m = press(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = press(m, tea.KeyMsg{Type: tea.KeyLeft})
+	if m.currentCheatsheet != 0 || !m.selectorRows[0].collapsed {
+		t.Errorf("Expected the cursor on the collapsed parent, got row %d", m.currentCheatsheet)
+	}
+
+	state, err := loadUIState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state.Collapsed, []string{m.selectorRows[0].group}) {
+		t.Errorf("Expected the collapsed group to be saved, got %v", state.Collapsed)
+	}
+
+	m = press(m, runes("v"))
+	if m.selectorGrouping != groupByCategory {
+		t.Errorf("Expected v to switch to the category view, got %s", m.selectorGrouping)
+	}
+}
+
+func TestUIStateRoundTrip(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	state, err := loadUIState()
+	if err != nil || !reflect.DeepEqual(state, uiState{}) {
+		t.Fatalf("Expected the zero state without a state file, got %+v, %v", state, err)
+	}
+
+	want := uiState{SelectorView: "category", Collapsed: []string{"category:VCS"}}
+	if err := saveUIState(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadUIState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	var m model
+	m.restoreUIState(got)
+	if m.selectorGrouping != groupByCategory || !m.collapsedGroups["category:VCS"] {
+		t.Errorf("Expected the state applied to the model, got %s %v", m.selectorGrouping, m.collapsedGroups)
+	}
+}
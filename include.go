@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sheetRoots are the cheatsheet roots, in the order they are layered: a sheet
+// in an earlier root hides one at the same relative path in a later root. The
+// sheets named by extends and include are looked up in them.
+var sheetRoots []string
+
+// splitRoots splits a list of cheatsheet roots, separated like $PATH
+func splitRoots(dirs string) []string {
+	var roots []string
+	for _, root := range filepath.SplitList(dirs) {
+		if root != "" {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// findSheets resolves a path written in the extends or include of the sheet
+// at from. Relative paths are looked up next to from first and then in each
+// of the sheetRoots. A glob matches in all of them; when two matches share a
+// relative path, the one found first wins. from itself never matches, so a
+// sheet can extend the one it hides in a later root.
+func findSheets(from, pattern string) ([]string, error) {
+	bases := []string{""}
+	if !filepath.IsAbs(pattern) {
+		bases = append([]string{filepath.Dir(from)}, sheetRoots...)
+	}
+
+	found := make(map[string]string) // relative path -> match
+	for _, base := range bases {
+		matches, err := filepath.Glob(filepath.Join(base, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || info.IsDir() || sameFile(match, from) {
+				continue
+			}
+			rel, err := filepath.Rel(base, match)
+			if base == "" || err != nil {
+				rel = match
+			}
+			if _, ok := found[rel]; !ok {
+				found[rel] = match
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no sheet matches %q", pattern)
+	}
+
+	var rels []string
+	for rel := range found {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	var paths []string
+	for _, rel := range rels {
+		paths = append(paths, found[rel])
+	}
+	return paths, nil
+}
+
+// resolveSheet builds the commands of a sheet loaded from path out of the
+// sheet it extends, the sheets it includes and its own commands, in that
+// order. A command replaces an earlier one of the same name in place; drop
+// removes inherited commands by name. chain holds the sheets being resolved,
+// outermost first, to catch cycles.
+func resolveSheet(sheet *CheatSheet, path string, chain []string) error {
+	if sheet.Extends == "" && len(sheet.Include) == 0 && len(sheet.Drop) == 0 {
+		return nil
+	}
+
+	var commands []Command
+	vars := make(map[string]string)
+	if sheet.Extends != "" {
+		paths, err := findSheets(path, sheet.Extends)
+		if err != nil {
+			return fmt.Errorf("extends: %w", err)
+		}
+		if len(paths) > 1 {
+			return fmt.Errorf("extends: %q matches %d sheets, a sheet extends only one", sheet.Extends, len(paths))
+		}
+		parent, err := loadResolvedSheet(paths[0], chain)
+		if err != nil {
+			return err
+		}
+		commands = mergeCommands(commands, parent.Commands, paths[0])
+		for name, value := range parent.Vars {
+			vars[name] = value
+		}
+		// Unset sheet fields are inherited too
+		if sheet.Title == "" {
+			sheet.Title = parent.Title
+		}
+		if sheet.Description == "" {
+			sheet.Description = parent.Description
+		}
+		if sheet.Category == "" {
+			sheet.Category = parent.Category
+		}
+	}
+
+	for _, pattern := range sheet.Include {
+		paths, err := findSheets(path, pattern)
+		if err != nil {
+			return fmt.Errorf("include: %w", err)
+		}
+		for _, included := range paths {
+			other, err := loadResolvedSheet(included, chain)
+			if err != nil {
+				return err
+			}
+			commands = mergeCommands(commands, other.Commands, included)
+			for name, value := range other.Vars {
+				vars[name] = value
+			}
+		}
+	}
+
+	// Variables are inherited like commands, the sheet's own winning
+	if len(vars) > 0 || sheet.Vars != nil {
+		for name, value := range sheet.Vars {
+			vars[name] = value
+		}
+		sheet.Vars = vars
+	}
+
+	inherited := make(map[string]bool)
+	for _, cmd := range commands {
+		inherited[cmd.Name] = true
+	}
+	commands = mergeCommands(commands, sheet.Commands, "")
+
+	for _, name := range sheet.Drop {
+		if !inherited[name] {
+			return fmt.Errorf("drop: no inherited command called %q", name)
+		}
+		for i, cmd := range commands {
+			if cmd.Name == name {
+				commands = append(commands[:i], commands[i+1:]...)
+				break
+			}
+		}
+	}
+	sheet.Commands = commands
+	return nil
+}
+
+// loadResolvedSheet loads the sheet at path for another sheet to build on,
+// failing when path is already being resolved further up the chain. A file
+// holding several sheets stands for its first.
+func loadResolvedSheet(path string, chain []string) (CheatSheet, error) {
+	for i, outer := range chain {
+		if sameFile(outer, path) {
+			return CheatSheet{}, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain[i:], " -> "), path)
+		}
+	}
+	sheets, err := decodeSheetFile(path)
+	if err != nil {
+		return CheatSheet{}, err
+	}
+	sheet := sheets[0]
+	if err := resolveSheet(&sheet, path, append(chain[:len(chain):len(chain)], path)); err != nil {
+		return CheatSheet{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return sheet, nil
+}
+
+// mergeCommands adds commands from the sheet at source to base. A command
+// named like one in base takes its place and records what it overrides.
+// Commands keep the sheet they were first defined in as their source; an
+// empty source stands for the sheet being resolved.
+func mergeCommands(base, commands []Command, source string) []Command {
+	merged := append([]Command(nil), base...)
+	for _, cmd := range commands {
+		if cmd.Source == "" {
+			cmd.Source = source
+		}
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == cmd.Name {
+				cmd.Overrides = merged[i].Source
+				merged[i] = cmd
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, cmd)
+		}
+	}
+	return merged
+}
+
+// sameFile reports whether a and b name the same file
+func sameFile(a, b string) bool {
+	ai, errA := os.Stat(a)
+	bi, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(ai, bi)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withRoots sets sheetRoots for the length of a test
+func withRoots(t *testing.T, roots ...string) {
+	t.Helper()
+	saved := sheetRoots
+	sheetRoots = roots
+	t.Cleanup(func() { sheetRoots = saved })
+}
+
+func commandNames(commands []Command) []string {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+func TestResolveSheet(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"kubectl.yaml": `title: kubectl
+category: Containers
+commands:
+  - name: get pods
+  - name: delete pod
+  - name: logs
+`,
+		"common/auth.yaml": "commands:\n  - name: login\n",
+		"common/vpn.md":    "## vpn up\n",
+		"prod.yaml": `title: kubectl (prod)
+extends: kubectl.yaml
+include: ["common/*"]
+drop: [delete pod]
+commands:
+  - name: logs
+    shortDesc: Logs from the prod cluster
+  - name: rollout
+`,
+	})
+
+	sheet, err := LoadCheatSheet(filepath.Join(dir, "prod.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"get pods", "logs", "login", "vpn up", "rollout"}
+	if got := commandNames(sheet.Commands); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected commands %v, got %v", want, got)
+	}
+	if sheet.Title != "kubectl (prod)" || sheet.Category != "Containers" {
+		t.Errorf("Expected the own title and the inherited category, got %q and %q", sheet.Title, sheet.Category)
+	}
+
+	base := filepath.Join(dir, "kubectl.yaml")
+	if got := sheet.Commands[0]; got.Source != base || got.Overrides != "" {
+		t.Errorf("Expected get pods from %s, got %q overriding %q", base, got.Source, got.Overrides)
+	}
+	if got := sheet.Commands[1]; got.ShortDesc != "Logs from the prod cluster" || got.Source != "" || got.Overrides != base {
+		t.Errorf("Expected the own logs overriding %s, got %+v", base, got)
+	}
+	if got := sheet.Commands[3]; got.Source != filepath.Join(dir, "common", "vpn.md") {
+		t.Errorf("Expected vpn up from common/vpn.md, got %q", got.Source)
+	}
+
+	detail := RenderCommandDetail(sheet.Commands[1], false)
+	if !strings.Contains(detail, "Overrides: "+base) {
+		t.Errorf("Expected the detail view to name the overridden sheet, got:\n%s", detail)
+	}
+}
+
+func TestResolveSheetErrors(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"a.yaml":       "extends: b.yaml\n",
+		"b.yaml":       "include: [c.yaml]\n",
+		"c.yaml":       "extends: a.yaml\n",
+		"drop.yaml":    "extends: base.yaml\ndrop: [missing]\n",
+		"base.yaml":    "commands:\n  - name: one\n",
+		"missing.yaml": "include: [nowhere/*.yaml]\n",
+		"two.yaml":     "extends: \"*.yaml\"\n",
+	})
+
+	tests := map[string]string{
+		"a.yaml":       "include cycle: " + filepath.Join(dir, "a.yaml") + " -> " + filepath.Join(dir, "b.yaml"),
+		"drop.yaml":    `drop: no inherited command called "missing"`,
+		"missing.yaml": `include: no sheet matches "nowhere/*.yaml"`,
+		"two.yaml":     "a sheet extends only one",
+	}
+	for name, want := range tests {
+		_, err := LoadCheatSheets(filepath.Join(dir, name))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, want, err)
+		}
+	}
+}
+
+func TestLayeredRoots(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"personal/kubectl.yaml":    "title: Mine\ncommands:\n  - name: mine\n",
+		"personal/prod.yaml":       "extends: kubectl.yaml\ninclude: [\"common/*.yaml\"]\n",
+		"personal/common/a.yaml":   "commands:\n  - name: personal a\n",
+		"team/kubectl.yaml":        "title: Team\ncommands:\n  - name: team\n",
+		"team/common/a.yaml":       "commands:\n  - name: team a\n",
+		"team/common/b.yaml":       "commands:\n  - name: team b\n",
+		"team/clusters/stage.yaml": "extends: kubectl.yaml\n",
+		"personal/git.yaml":        "extends: git.yaml\ncommands:\n  - name: my alias\n",
+		"team/git.yaml":            "title: Git\ncommands:\n  - name: team git\n",
+	})
+	personal, team := filepath.Join(dir, "personal"), filepath.Join(dir, "team")
+	withRoots(t, personal, team)
+
+	sheet, err := LoadCheatSheet(filepath.Join(personal, "prod.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"mine", "personal a", "team b"}; !reflect.DeepEqual(commandNames(sheet.Commands), want) {
+		t.Errorf("Expected the personal sheets layered over the team ones, %v, got %v", want, commandNames(sheet.Commands))
+	}
+
+	// A sheet in a later root finds its base in an earlier one
+	stage, err := LoadCheatSheet(filepath.Join(team, "clusters", "stage.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stage.Title != "Mine" {
+		t.Errorf("Expected stage to extend the personal kubectl.yaml, got %q", stage.Title)
+	}
+
+	// A sheet extends the one of the same name it hides in a later root
+	git, err := LoadCheatSheet(filepath.Join(personal, "git.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"team git", "my alias"}; git.Title != "Git" || !reflect.DeepEqual(commandNames(git.Commands), want) {
+		t.Errorf("Expected the personal git.yaml to build on the team one, got %q with %v", git.Title, commandNames(git.Commands))
+	}
+
+	msg := loadCheatsheetsMsg(strings.Join([]string{personal, team, filepath.Join(dir, "missing")}, string(filepath.ListSeparator)))
+	infos, ok := msg.(cheatsheetsLoadedMsg)
+	if !ok {
+		t.Fatalf("Expected the sheets of both roots, got %v", msg)
+	}
+	var listed []string
+	for _, info := range infos {
+		listed = append(listed, filepath.Join(filepath.Base(info.Root), info.Path))
+	}
+	want := []string{"team/clusters/stage.yaml", "personal/common/a.yaml", "team/common/b.yaml", "personal/git.yaml", "personal/kubectl.yaml", "personal/prod.yaml"}
+	if !reflect.DeepEqual(listed, want) {
+		t.Errorf("Expected %v, got %v", want, listed)
+	}
+}
+
+func TestConvertKeepsExtends(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"base.yaml": "commands:\n  - name: one\n",
+		"prod.yaml": "title: Prod\nextends: base.yaml\ndrop: [one]\ncommands:\n  - name: two\n",
+	})
+	var stdout, stderr bytes.Buffer
+	if status := runConvert([]string{"--to", "json", filepath.Join(dir, "prod.yaml")}, &stdout, &stderr); status != 0 {
+		t.Fatalf("convert failed with status %d: %s", status, stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, `"extends": "base.yaml"`) || strings.Contains(out, `"name": "one"`) {
+		t.Errorf("Expected extends kept and not resolved, got:\n%s", out)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// lintCheck looks for problems in a loaded sheet, describing each in a line
+type lintCheck func(sheet CheatSheet) []string
+
+// lintChecks are run on every sheet by "cheatcheat lint"
+var lintChecks = []lintCheck{lintVars, lintDeprecated, lintAliases}
+
+// lintVars reports the variables without a value that text refers to, in
+// sheets that declare vars or when there are overrides, and templates that
+// don't parse in sheets that declare vars
+func lintVars(sheet CheatSheet) []string {
+	vars := sheetVars(sheet)
+	if vars == nil {
+		return nil
+	}
+	var problems []string
+	check := func(cmd Command, field, text string) {
+		missing, err := unresolvedVars(text, vars)
+		if err != nil && sheet.Vars != nil {
+			problems = append(problems, fmt.Sprintf("command %q: %s: %v", cmd.Name, field, err))
+		}
+		for _, name := range missing {
+			problems = append(problems, fmt.Sprintf("command %q: %s: unresolved variable %q", cmd.Name, field, name))
+		}
+	}
+	for _, cmd := range sheet.Commands {
+		check(cmd, "syntax", cmd.Syntax)
+		for i, ex := range cmd.Examples {
+			check(cmd, fmt.Sprintf("example %d", i+1), ex.Code)
+		}
+		for i, note := range cmd.Notes {
+			check(cmd, fmt.Sprintf("note %d", i+1), note)
+		}
+	}
+	return problems
+}
+
+// lintDeprecated reports deprecated commands whose replacement isn't a command
+// of the sheet, by name or alias
+func lintDeprecated(sheet CheatSheet) []string {
+	var problems []string
+	for _, cmd := range sheet.Commands {
+		d := cmd.Deprecated
+		if d == nil || d.Replacement == "" {
+			continue
+		}
+		if _, ok := findCommand(sheet.Commands, d.Replacement); !ok {
+			problems = append(problems, fmt.Sprintf("command %q: deprecated: no command called %q to replace it", cmd.Name, d.Replacement))
+		}
+	}
+	return problems
+}
+
+// lintAliases reports aliases that are the name of another command or an
+// alias of an earlier one, which would make related and search ambiguous
+func lintAliases(sheet CheatSheet) []string {
+	names := make(map[string]bool)
+	for _, cmd := range sheet.Commands {
+		names[cmd.Name] = true
+	}
+	var problems []string
+	owners := make(map[string]string) // alias -> command that has it
+	for _, cmd := range sheet.Commands {
+		for _, alias := range cmd.Aliases {
+			switch owner, taken := owners[alias]; {
+			case names[alias]:
+				problems = append(problems, fmt.Sprintf("command %q: alias %q is the name of a command", cmd.Name, alias))
+			case taken && owner != cmd.Name:
+				problems = append(problems, fmt.Sprintf("command %q: alias %q is also an alias of command %q", cmd.Name, alias, owner))
+			case !taken:
+				owners[alias] = cmd.Name
+			}
+		}
+	}
+	return problems
+}
+
+// runLint implements "cheatcheat lint": it loads each sheet, or those in the
+// cheatsheet roots when no paths are given, and reports what fails to load
+// and what the lintChecks find. It exits non-zero when anything was reported.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	vars := make(varFlag)
+	flags.Var(vars, "var", "set a sheet variable, as name=value; may be repeated")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat lint [--var name=value] [paths...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	saved := varOverrides
+	varOverrides = overrideVars(saved, nil, vars)
+	defer func() { varOverrides = saved }()
+
+	targets := flags.Args()
+	if len(targets) == 0 {
+		for _, root := range sheetRoots {
+			if _, err := os.Stat(root); err == nil {
+				targets = append(targets, root)
+			}
+		}
+	}
+	paths, err := sheetPaths(targets)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range paths {
+		sheets, err := LoadCheatSheets(path)
+		if err != nil {
+			fmt.Fprintln(stdout, err)
+			status = 1
+			continue
+		}
+		for i, sheet := range sheets {
+			for _, check := range lintChecks {
+				for _, problem := range check(sheet) {
+					if len(sheets) > 1 {
+						problem = fmt.Sprintf("document %d: %s", i+1, problem)
+					}
+					fmt.Fprintf(stdout, "%s: %s\n", path, problem)
+					status = 1
+				}
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRenderCommandWindowMatchesFullList(t *testing.T) {
+	m := newListModel(20, 200, 40)
+	desc := m.cheatSheet.Description
+	full := strings.Split(RenderCommandList(desc, m.commands, 7), "\n")
+
+	for _, offset := range []int{0, 1, 5, 12, 30} {
+		height := 9
+		window := strings.Split(RenderCommandWindow(desc, m.commands, 7, offset, height, 0), "\n")
+		if len(window) != height {
+			t.Fatalf("offset %d: expected %d lines, got %d", offset, height, len(window))
+		}
+		for i, line := range window {
+			want := ""
+			if offset+i < len(full) {
+				want = full[offset+i]
+			}
+			if strings.TrimRight(line, " ") != strings.TrimRight(want, " ") {
+				t.Errorf("offset %d line %d: got %q, want %q", offset, i, line, want)
+			}
+		}
+	}
+}
+
+func TestSearchIndexFilter(t *testing.T) {
+	idx := newSearchIndex([]Command{
+		{Name: "Git Status"},
+		{Name: "git commit"},
+		{Name: "kubectl get pods"},
+	})
+
+	if got := idx.Filter("GIT", commandFilter{}); len(got) != 2 {
+		t.Errorf("Expected 2 matches for 'GIT', got %d", len(got))
+	}
+	if got := idx.Filter("", commandFilter{}); len(got) != 3 {
+		t.Errorf("Expected all 3 commands for an empty query, got %d", len(got))
+	}
+}
+
+// benchmarkSizes covers small sheets up to merged multi-thousand command views
+var benchmarkSizes = []int{100, 1000, 10000}
+
+func BenchmarkListKeystroke(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("commands=%d", n), func(b *testing.B) {
+			m := newListModel(n, 120, 50)
+			down := tea.KeyMsg{Type: tea.KeyDown}
+			up := tea.KeyMsg{Type: tea.KeyUp}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				k := down
+				if m.currentCommand == n-1 || (i/n)%2 == 1 {
+					k = up
+				}
+				tm, _ := m.Update(k)
+				m = tm.(model)
+				_ = m.View()
+			}
+		})
+	}
+}
+
+func BenchmarkSearchKeystroke(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(fmt.Sprintf("commands=%d", n), func(b *testing.B) {
+			m := newListModel(n, 120, 50)
+			m = press(m, runes("/"))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m = press(m, runes("d"))
+				_ = m.View()
+				m = press(m, tea.KeyMsg{Type: tea.KeyBackspace})
+				_ = m.View()
+			}
+		})
+	}
+}
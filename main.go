@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -17,8 +21,6 @@ import (
 )
 
 func main() {
-	startLogging()
-
 	// Get default directory from environment variable or use "cheatsheets"
 	defaultDir := os.Getenv("CHEATSHEET_DIR")
 	if defaultDir == "" {
@@ -26,22 +28,60 @@ func main() {
 	}
 
 	// Define command-line flags
-	cheatsheetDir := flag.String("dir", defaultDir, "Directory containing cheatsheet files")
+	cheatsheetDir := flag.String("dir", defaultDir, "Directory containing cheatsheet files, or a list of them separated like $PATH, earlier ones layered over later ones")
+	vars := make(varFlag)
+	flag.Var(vars, "var", "Set a sheet variable, as name=value; may be repeated")
 	flag.Parse()
 	args := flag.Args()
+	sheetRoots = splitRoots(*cheatsheetDir)
+
+	// Sheet variables set outside the sheets
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring config file: %v\n", err)
+	}
+	varOverrides = overrideVars(cfg.Vars, os.Environ(), vars)
+
+	// Subcommands run without the TUI
+	if len(args) >= 1 {
+		if args[0] == completeCommand {
+			os.Exit(runComplete(args[1:], os.Stdout, os.Stderr))
+		}
+		if cmd, ok := subcommands[args[0]]; ok {
+			os.Exit(cmd.run(args[1:], os.Stdout, os.Stderr))
+		}
+	}
+
+	startLogging()
 
 	// Create initial model based on whether a file path was provided
 	var m model
 	if len(args) >= 1 {
 		// File path provided - load it directly (backward compatible)
 		m = initialModel(args[0], *cheatsheetDir)
+		if len(args) >= 2 {
+			m.openCommand = args[1]
+		}
 	} else {
 		// No file path - show cheatsheet selector
 		m = initialModelWithSelector(*cheatsheetDir)
 	}
 
+	// Restore the selector layout from the previous session
+	state, err := loadUIState()
+	if err != nil {
+		logrus.Warnf("Failed to load UI state: %v", err)
+	}
+	m.restoreUIState(state)
+
+	// Layer the user's own annotations over the shared sheets
+	m.annotations, err = loadAnnotations()
+	if err != nil {
+		logrus.Warnf("Failed to load annotations: %v", err)
+	}
+
 	// Create and run the Bubble Tea program
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
@@ -53,7 +93,7 @@ func initialModel(filePath string, cheatsheetDir string) model {
 	width, _, _ := term.GetSize(os.Stdout.Fd())
 	vp := viewport.New(width, 24)
 	vp.SetContent("Loading cheat sheet...")
-	tagVp := viewport.New(width, 3)
+	tagVp := viewport.New(width, tagBarHeight)
 	tagVp.SetContent("Loading tags...")
 	// Create initial model
 	m := model{
@@ -63,6 +103,7 @@ func initialModel(filePath string, cheatsheetDir string) model {
 		showDetail:             false,
 		showCheatsheetSelector: false,
 		cheatsheetDir:          cheatsheetDir,
+		collapsedGroups:        make(map[string]bool),
 	}
 
 	// Load the cheat sheet in the Init function
@@ -74,7 +115,7 @@ func initialModelWithSelector(cheatsheetDir string) model {
 	width, _, _ := term.GetSize(os.Stdout.Fd())
 	vp := viewport.New(width, 24)
 	vp.SetContent("Loading cheatsheets...")
-	tagVp := viewport.New(width, 3)
+	tagVp := viewport.New(width, tagBarHeight)
 	tagVp.SetContent("")
 	// Create initial model with selector enabled
 	m := model{
@@ -85,6 +126,7 @@ func initialModelWithSelector(cheatsheetDir string) model {
 		showDetail:             false,
 		showCheatsheetSelector: true,
 		cheatsheetDir:          cheatsheetDir,
+		collapsedGroups:        make(map[string]bool),
 	}
 
 	return m
@@ -99,33 +141,51 @@ func (m model) Init() tea.Cmd {
 	}
 	// Load specific cheatsheet file
 	return func() tea.Msg {
-		return loadCheatSheetMsg(flag.Arg(0))
+		return loadCheatSheetMsg(flag.Arg(0), 0)
 	}
 }
 
 // Custom message types
 type errorMsg struct{ err error }
 type cheatSheetLoadedMsg CheatSheet
-type cheatsheetsLoadedMsg []string
+type cheatsheetsLoadedMsg []sheetInfo
 
 func (e errorMsg) Error() string { return e.err.Error() }
 
-// Command to load a cheat sheet
-func loadCheatSheetMsg(filePath string) tea.Msg {
-	sheet, err := LoadCheatSheet(filePath)
+// Command to load a cheat sheet, the doc'th of its file
+func loadCheatSheetMsg(filePath string, doc int) tea.Msg {
+	sheet, err := loadCheatSheetAt(filePath, doc)
 	if err != nil {
 		return errorMsg{err}
 	}
 	return cheatSheetLoadedMsg(sheet)
 }
 
-// Command to discover cheatsheets in a directory
-func loadCheatsheetsMsg(dir string) tea.Msg {
-	cheatsheets, err := DiscoverCheatsheets(dir)
-	if err != nil {
-		return errorMsg{err}
+// Command to discover cheatsheets in each root of a list of directories and
+// load their metadata. A sheet hides those at the same relative path in later
+// roots; roots after the first may be missing.
+func loadCheatsheetsMsg(dirs string) tea.Msg {
+	var infos []sheetInfo
+	seen := make(map[string]bool)
+	for i, root := range splitRoots(dirs) {
+		cheatsheets, err := DiscoverCheatsheets(root)
+		if i > 0 && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return errorMsg{err}
+		}
+		var visible []string
+		for _, path := range cheatsheets {
+			if !seen[path] {
+				seen[path] = true
+				visible = append(visible, path)
+			}
+		}
+		infos = append(infos, loadSheetInfos(root, visible)...)
 	}
-	return cheatsheetsLoadedMsg(cheatsheets)
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return cheatsheetsLoadedMsg(infos)
 }
 
 // Return a list of unique tags from commands
@@ -148,36 +208,283 @@ func UniqueTags(commands []Command) []string {
 	return tags
 }
 
-func filterCommandsByTag(commands []Command, tag string) []Command {
-    if tag == "all" {
-        return commands
-    }
+// isDigits reports whether s is a non-empty run of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// selectCommand moves the command list cursor to idx, clamped to the list,
+// and scrolls it into view
+func (m *model) selectCommand(idx int) {
+	if idx > len(m.commands)-1 {
+		idx = len(m.commands) - 1
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	m.currentCommand = idx
+	m.scrollToSelection()
+}
+
+// scrollToSelection scrolls the command list so the selected command stays on
+// screen. The list itself is drawn by View, one window at a time.
+func (m *model) scrollToSelection() {
+	offset := m.listOffset
+	if m.currentCommand == 0 {
+		// Keep the sheet description in view at the top of the list, room
+		// permitting
+		offset = 0
+	}
+	line := commandListLine(m.cheatSheet.Description, m.currentCommand)
+	offset = scrollOffsetFor(line, offset, m.viewport.Height)
+	maxOffset := commandListLines(m.cheatSheet.Description, len(m.commands)) - m.viewport.Height
+	m.listOffset = max(0, min(offset, maxOffset))
+}
 
-    var filtered []Command
-    for _, cmd := range commands {
-        for _, t := range cmd.Tags {
-            if t == tag {
-                filtered = append(filtered, cmd)
-                break
-            }
-        }
-    }
-    return filtered
+// listVisible reports whether the main area shows the command list
+func (m model) listVisible() bool {
+	return !m.showCheatsheetSelector && !m.showDetail && len(m.cheatSheet.Commands) > 0
 }
 
-func filterCommandsBySearch(commands []Command, query string) []Command {
-	if query == "" {
-		return commands
+// selectCheatsheet moves the selector cursor to idx and redraws the selector
+func (m *model) selectCheatsheet(idx int) {
+	m.currentCheatsheet = idx
+	content := RenderCheatsheetList(m.selectorRows, m.currentCheatsheet, m.sheetQuery)
+	m.viewport.SetContent(content)
+	line := cheatsheetListLine(m.currentCheatsheet)
+	m.viewport.SetYOffset(scrollOffsetFor(line, m.viewport.YOffset, m.viewport.Height))
+}
+
+// scrollOffsetFor returns the viewport offset that brings line into a view of
+// the given height, moving the current offset as little as possible
+func scrollOffsetFor(line, offset, height int) int {
+	if height <= 0 {
+		return offset
+	}
+	if line < offset {
+		return line
 	}
+	if line >= offset+height {
+		return line - height + 1
+	}
+	return offset
+}
 
-	var filtered []Command
-	lowerQuery := strings.ToLower(query)
-	for _, cmd := range commands {
-		if strings.Contains(strings.ToLower(cmd.Name), lowerQuery) {
-			filtered = append(filtered, cmd)
+// commandsPerPage returns how many command rows fit in the viewport, each
+// command taking a line plus a blank separator
+func (m model) commandsPerPage() int {
+	if n := m.viewport.Height / 2; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// jumpToNumber selects the command with the 1-based number typed by the user,
+// as printed by RenderCommandList
+func (m *model) jumpToNumber() {
+	n, err := strconv.Atoi(m.jumpInput)
+	m.jumpInput = ""
+	if err != nil || n < 1 || n > len(m.commands) {
+		return
+	}
+	m.selectCommand(n - 1)
+}
+
+// openDetail switches to the detail view of the selected command, with long
+// example outputs truncated
+func (m *model) openDetail() {
+	m.showDetail = true
+	m.expandOutput = false
+	m.renderDetail()
+	m.viewport.GotoTop()
+}
+
+// renderDetail renders the detail view of the selected command
+func (m *model) renderDetail() {
+	cmd := resolveRelated(m.commands[m.currentCommand], m.cheatSheet.Commands)
+	m.viewport.SetContent(RenderCommandDetail(expandCommand(cmd, m.vars), m.expandOutput))
+}
+
+// loadSelectedCheatsheet returns a command that loads the cheatsheet under the
+// selector cursor
+func (m model) loadSelectedCheatsheet() tea.Cmd {
+	info := m.selectorRows[m.currentCheatsheet].sheet
+	filePath := filepath.Join(info.Root, info.Path)
+	return func() tea.Msg {
+		return loadCheatSheetMsg(filePath, info.Document)
+	}
+}
+
+// selectTag moves the tag cursor to idx. While no tags are toggled the tag
+// under the cursor is the filter, as with the original single-tag menu.
+func (m *model) selectTag(idx int) {
+	m.currentTag = idx
+	logrus.Debugf("box size set: width=%d, height=%d", m.width, m.height)
+	if m.tagFilter.Empty() {
+		m.applyFilters()
+	} else {
+		m.refreshTagBar()
+	}
+}
+
+// setTagFilter replaces the toggled tag filter and re-applies it
+func (m *model) setTagFilter(f tagFilter) {
+	m.tagFilter = f
+	m.applyFilters()
+}
+
+// activeTagFilter returns the tag filter in effect: the toggled tags, or the
+// tag under the cursor when nothing has been toggled
+func (m model) activeTagFilter() tagFilter {
+	if !m.tagFilter.Empty() || len(m.tagMenu) == 0 {
+		return m.tagFilter
+	}
+	if tag := m.tagMenu[m.currentTag]; tag != "all" {
+		return tagFilter{include: []string{tag}}
+	}
+	return tagFilter{}
+}
+
+// commandFilter returns everything besides the search query that currently
+// narrows the command list
+func (m model) commandFilter() commandFilter {
+	f := commandFilter{tags: m.activeTagFilter(), complexity: m.complexityFilter}
+	if m.thisPlatformOnly {
+		f.platform = runtime.GOOS
+	}
+	if m.versionMatchOnly {
+		f.versions = m.toolVersions
+		if f.versions == nil {
+			f.versions = map[string]string{}
+		}
+	}
+	f.available = m.availableOnly
+	return f
+}
+
+// applyFilters recomputes the visible commands from the tag filter and search
+// query together, resets the cursor and redraws the tag bar
+func (m *model) applyFilters() {
+	m.commands = m.searchIndex.Filter(m.searchQuery, m.commandFilter())
+	if m.sortByComplexity {
+		m.commands = sortByComplexity(m.commands)
+	}
+	m.currentCommand = 0 // Reset the cursor
+	m.scrollToSelection()
+	m.refreshTagBar()
+}
+
+// setCheatSheet shows sheet's command list with every filter and search
+// cleared
+func (m *model) setCheatSheet(sheet CheatSheet) {
+	sheet.Commands = markMissing(sheet.Commands, m.binaries)
+	sheet.Commands = annotateCommands(sheet.Commands, m.annotations[annotationKey(sheet)])
+	m.cheatSheet = sheet
+	m.vars = sheetVars(sheet)
+	m.searchIndex = newSearchIndex(m.cheatSheet.Commands)
+	m.tagMenu = UniqueTags(taggedCommands(m.cheatSheet.Commands))
+	m.currentTag = 0
+	m.tagFilter = tagFilter{}
+	m.complexityFilter = complexityFilter{}
+	m.searchMode = false
+	m.searchActive = false
+	m.searchQuery = ""
+	m.showDetail = false
+	m.showCheatsheetSelector = false // Exit selector mode
+	// Update the view with the command list
+	logrus.Debugf("Window size set: width=%d, height=%d", m.width, m.height)
+	m.applyFilters()
+	if len(m.commands) == 0 {
+		m.viewport.SetContent("No commands found in the cheat sheet.")
+	}
+}
+
+// markMissing marks the commands of the sheet with the programs found missing
+// so far. The list is filtered again, keeping the selected command, unless
+// the detail view is showing one of them.
+func (m *model) markMissing() {
+	m.cheatSheet.Commands = markMissing(m.cheatSheet.Commands, m.binaries)
+	m.searchIndex = newSearchIndex(m.cheatSheet.Commands)
+	if m.showDetail {
+		m.commands = markMissing(m.commands, m.binaries)
+		return
+	}
+	name := ""
+	if m.currentCommand < len(m.commands) {
+		name = m.commands[m.currentCommand].Name
+	}
+	m.applyFilters()
+	for i, cmd := range m.commands {
+		if cmd.Name == name {
+			m.selectCommand(i)
+			break
+		}
+	}
+}
+
+// showCommand opens the detail view of the command called name, or with name
+// as an alias, clearing the search and filters when they hide it. It reports
+// whether the sheet has the command.
+func (m *model) showCommand(name string) bool {
+	if cmd, ok := findCommand(m.cheatSheet.Commands, name); ok {
+		name = cmd.Name
+	}
+	find := func() int {
+		for i, cmd := range m.commands {
+			if cmd.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	i := find()
+	if i < 0 {
+		m.searchQuery, m.searchActive = "", false
+		m.tagFilter = tagFilter{}
+		m.complexityFilter = complexityFilter{}
+		m.thisPlatformOnly, m.versionMatchOnly, m.availableOnly = false, false, false
+		m.applyFilters()
+		if i = find(); i < 0 {
+			return false
 		}
 	}
-	return filtered
+	m.selectCommand(i)
+	m.openDetail()
+	return true
+}
+
+// openInitialCommand shows the command named on the command line once its
+// sheet has loaded, or searches for the name when the sheet has no such
+// command
+func (m *model) openInitialCommand() {
+	if m.openCommand == "" {
+		return
+	}
+	if !m.showCommand(m.openCommand) {
+		m.searchQuery, m.searchActive = m.openCommand, true
+		m.applyFilters()
+	}
+	m.openCommand = ""
+}
+
+// refreshTagBar redraws the tag bar: the tags with the cursor and toggles, and
+// a status line with the filter expression and search
+func (m *model) refreshTagBar() {
+	if len(m.tagMenu) == 0 {
+		return
+	}
+	menu := RenderTagMenu(m.tagMenu, m.currentTag, m.width, m.tagFilter)
+	status := RenderFilterStatus(m.commandFilter(), m.sortByComplexity, m.searchQuery, m.searchActive, len(m.commands), m.width)
+	content := lipgloss.Style(boxedViewportStyle).Render(menu + "\n" + status)
+	m.tagViewPort.SetContent(content)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -187,29 +494,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		// Handle cheatsheet selector mode
 		if m.showCheatsheetSelector {
+			return m.updateSelector(msg)
+		}
+
+		if m.form != nil {
+			return m.updateForm(msg)
+		}
+
+		// An edit left the sheet broken: edit again or keep the loaded version
+		if m.editErr != nil {
 			switch {
 			case key.Matches(msg, keys.Quit):
 				return m, tea.Quit
-			case key.Matches(msg, keys.Up):
-				if m.currentCheatsheet > 0 {
-					m.currentCheatsheet--
-					content := RenderCheatsheetList(m.cheatsheets, m.currentCheatsheet)
-					m.viewport.SetContent(content)
-				}
-			case key.Matches(msg, keys.Down):
-				if m.currentCheatsheet < len(m.cheatsheets)-1 {
-					m.currentCheatsheet++
-					content := RenderCheatsheetList(m.cheatsheets, m.currentCheatsheet)
-					m.viewport.SetContent(content)
-				}
-			case key.Matches(msg, keys.Enter):
-				if len(m.cheatsheets) > 0 {
-					// Load the selected cheatsheet
-					filePath := filepath.Join(m.cheatsheetDir, m.cheatsheets[m.currentCheatsheet])
-					return m, func() tea.Msg {
-						return loadCheatSheetMsg(filePath)
-					}
+			case key.Matches(msg, keys.Edit):
+				if m.annotationEdit != nil {
+					return m, m.reEditAnnotation()
 				}
+				return m, m.reEdit()
+			case key.Matches(msg, keys.Back):
+				m.endAnnotationEdit()
 			}
 			return m, nil
 		}
@@ -220,34 +523,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case key.Matches(msg, keys.Enter):
 				// Exit search mode (filtering already done live)
 				m.searchMode = false
-				m.searchActive = true
+				m.searchActive = m.searchQuery != ""
+				m.refreshTagBar()
 				return m, nil
 			case key.Matches(msg, keys.Back):
 				// Cancel search
 				m.searchMode = false
 				m.searchQuery = ""
+				m.applyFilters()
 				return m, nil
 			case msg.Type == tea.KeyBackspace:
 				// Remove last character from search query and filter live
 				if len(m.searchQuery) > 0 {
 					m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
 				}
-				m.commands = filterCommandsBySearch(m.cheatSheet.Commands, m.searchQuery)
-				m.currentCommand = 0
-				content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-				m.viewport.SetContent(content)
+				m.applyFilters()
 				return m, nil
 			case msg.Type == tea.KeyRunes:
 				// Add character to search query and filter live
 				m.searchQuery += string(msg.Runes)
-				m.commands = filterCommandsBySearch(m.cheatSheet.Commands, m.searchQuery)
-				m.currentCommand = 0
-				content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-				m.viewport.SetContent(content)
+				m.applyFilters()
 				return m, nil
 			}
 		}
 
+		// Typing digits in the command list starts a jump to that command number
+		if !m.showDetail && msg.Type == tea.KeyRunes && isDigits(string(msg.Runes)) {
+			m.jumpInput += string(msg.Runes)
+			return m, nil
+		}
+		if m.jumpInput != "" {
+			if key.Matches(msg, keys.Enter) {
+				m.jumpToNumber()
+			}
+			// Any other key cancels the jump
+			m.jumpInput = ""
+			return m, nil
+		}
+
 		switch {
 		case key.Matches(msg, keys.Quit):
 			return m, tea.Quit
@@ -260,6 +573,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return loadCheatsheetsMsg(m.cheatsheetDir)
 			}
 
+		case key.Matches(msg, keys.Edit):
+			// Fix the selected command in $EDITOR
+			return m, m.editSelected()
+
+		case key.Matches(msg, keys.AddCommand):
+			if m.cheatSheet.Path != "" {
+				m.openForm(Command{}, "")
+			}
+
+		case key.Matches(msg, keys.EditForm):
+			if m.cheatSheet.Path != "" && len(m.commands) > 0 {
+				cmd := m.commands[m.currentCommand]
+				m.openForm(cmd, cmd.Name)
+			}
+
 		case key.Matches(msg, keys.Search):
 			if !m.showDetail && len(m.commands) > 0 {
 				// Enter search mode
@@ -271,37 +599,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keys.Enter):
 			if !m.showDetail && len(m.commands) > 0 {
 				// Show detail view of the selected command
-				m.showDetail = true
-				content := RenderCommandDetail(m.commands[m.currentCommand])
-				m.viewport.SetContent(content)
-				m.viewport.GotoTop()
+				m.openDetail()
 			}
 
 		case key.Matches(msg, keys.Back):
 			if m.searchActive {
-				// Clear search filter and restore full list
+				// Clear search filter, keeping the tag filter
 				m.searchActive = false
 				m.searchQuery = ""
-				m.commands = filterCommandsByTag(m.cheatSheet.Commands, m.tagMenu[m.currentTag])
-				m.currentCommand = 0
-				content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-				m.viewport.SetContent(content)
+				m.applyFilters()
 			} else if m.showDetail {
-				// Go back to command list view
+				// Go back to command list view, scrolled to the command we left
 				m.showDetail = false
-				content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-				m.viewport.SetContent(content)
+				m.tagViewPort.SetXOffset(0)
 				m.viewport.GotoTop()
+				m.scrollToSelection()
+			} else if !m.tagFilter.Empty() {
+				// Clear the toggled tags
+				m.setTagFilter(tagFilter{})
 			}
 
 		case key.Matches(msg, keys.Up):
 			if !m.showDetail {
 				// Navigate up in the command list
 				if m.currentCommand > 0 {
-					m.currentCommand--
-					// Update the content to reflect the new selection
-					content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-					m.viewport.SetContent(content)
+					m.selectCommand(m.currentCommand - 1)
 				}
 			} else {
 				// Scroll up in the viewport
@@ -312,89 +634,192 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if !m.showDetail {
 				// Navigate down in the command list
 				if m.currentCommand < len(m.commands)-1 {
-					m.currentCommand++
-					// Update the content to reflect the new selection
-					content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-					m.viewport.SetContent(content)
-
+					m.selectCommand(m.currentCommand + 1)
 				}
 			} else {
 				// Scroll down in the viewport
 				m.viewport.ScrollDown(1)
 			}
 
+		case key.Matches(msg, keys.PageUp):
+			// The viewport pages through the detail view by itself
+			if !m.showDetail {
+				m.selectCommand(m.currentCommand - m.commandsPerPage())
+			}
+
+		case key.Matches(msg, keys.PageDown):
+			if !m.showDetail {
+				m.selectCommand(m.currentCommand + m.commandsPerPage())
+			}
+
+		case key.Matches(msg, keys.Home):
+			if !m.showDetail {
+				m.selectCommand(0)
+			} else {
+				m.viewport.GotoTop()
+			}
+
+		case key.Matches(msg, keys.End):
+			if !m.showDetail {
+				m.selectCommand(len(m.commands) - 1)
+			} else {
+				m.viewport.GotoBottom()
+			}
+
+		case key.Matches(msg, keys.ToggleTag):
+			if !m.showDetail && len(m.tagMenu) > 0 {
+				if tag := m.tagMenu[m.currentTag]; tag == "all" {
+					// Toggling "all" drops every toggled tag
+					m.setTagFilter(tagFilter{})
+				} else {
+					m.setTagFilter(m.tagFilter.Toggle(tag))
+				}
+			}
+
+		case key.Matches(msg, keys.ExcludeTag):
+			if !m.showDetail && len(m.tagMenu) > 0 {
+				if tag := m.tagMenu[m.currentTag]; tag != "all" {
+					m.setTagFilter(m.tagFilter.ToggleExclude(tag))
+				}
+			}
+
+		case key.Matches(msg, keys.TagMode):
+			if !m.showDetail {
+				m.setTagFilter(m.tagFilter.ToggleMode())
+			}
+
+		case key.Matches(msg, keys.Complexity):
+			if !m.showDetail {
+				m.complexityFilter = m.complexityFilter.Next()
+				m.applyFilters()
+			}
+
+		case key.Matches(msg, keys.ComplexityExact):
+			if !m.showDetail {
+				m.complexityFilter = m.complexityFilter.ToggleExact()
+				m.applyFilters()
+			}
+
+		case key.Matches(msg, keys.Platform):
+			if !m.showDetail {
+				m.thisPlatformOnly = !m.thisPlatformOnly
+				m.applyFilters()
+			}
+
+		case key.Matches(msg, keys.Versions):
+			if !m.showDetail {
+				m.versionMatchOnly = !m.versionMatchOnly
+				m.applyFilters()
+				if m.versionMatchOnly {
+					return m, detectToolVersions(m.cheatSheet.Commands, m.toolVersions)
+				}
+			}
+
+		case key.Matches(msg, keys.Available):
+			if !m.showDetail {
+				m.availableOnly = !m.availableOnly
+				m.applyFilters()
+			}
+
+		case key.Matches(msg, keys.Annotate):
+			// Write private notes on the selected command in $EDITOR
+			return m, m.annotateSelected()
+
+		case key.Matches(msg, keys.ExpandOutput):
+			if m.showDetail && m.truncatesOutput() {
+				m.expandOutput = !m.expandOutput
+				m.renderDetail()
+			}
+
+		case key.Matches(msg, keys.Replacement):
+			if m.showDetail && m.showsDeprecated() {
+				m.showCommand(m.commands[m.currentCommand].Deprecated.Replacement)
+			}
+
+		case key.Matches(msg, keys.SortComplexity):
+			if !m.showDetail {
+				m.sortByComplexity = !m.sortByComplexity
+				m.applyFilters()
+			}
+
 		case key.Matches(msg, keys.Right):
-			if !m.showDetail && !m.searchActive {
-				// Navigate right on the tags (disabled when search is active)
+			if !m.showDetail {
+				// Navigate right on the tags
 				if m.currentTag < len(m.tagMenu)-1 {
-					m.currentTag++
-					m.commands = filterCommandsByTag(m.cheatSheet.Commands, m.tagMenu[m.currentTag])
-					m.currentCommand = 0 // Reset the cursor
-					content := lipgloss.Style(boxedViewportStyle).Render(RenderTagMenu(m.tagMenu, m.currentTag, m.width))
-					m.tagViewPort.SetContent(content)
-					content = RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-					m.viewport.SetContent(content)
+					m.selectTag(m.currentTag + 1)
 				}
 			} else if m.showDetail {
 				m.tagViewPort.ScrollRight(10)
 			}
 		case key.Matches(msg, keys.Left):
-			if !m.showDetail && !m.searchActive {
-				// Navigate left on the tags (disabled when search is active)
+			if !m.showDetail {
+				// Navigate left on the tags
 				if m.currentTag > 0 {
-					m.currentTag--
-					m.commands = filterCommandsByTag(m.cheatSheet.Commands, m.tagMenu[m.currentTag])
-					m.currentCommand = 0 // Reset the cursor
-					logrus.Debugf("box size set: width=%d, height=%d", m.width, m.height)
-					content := lipgloss.Style(boxedViewportStyle).Render(RenderTagMenu(m.tagMenu, m.currentTag, m.width))
-					m.tagViewPort.SetContent(content)
-					content = RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-					m.viewport.SetContent(content)
+					m.selectTag(m.currentTag - 1)
 				}
 			} else if m.showDetail {
 				m.tagViewPort.ScrollLeft(10)
 			}
 		}
 
+	case tea.MouseMsg:
+		if m.err == nil {
+			return m.handleMouse(msg)
+		}
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		logrus.Debugf("Window size changed: width=%d, height=%d", msg.Width, msg.Height)
-		headerHeight := 9 // Reserve space for header/footer
+		headerHeight := 7 + tagBarHeight // Reserve space for header/footer
 		m.viewport.Width = msg.Width
 		m.viewport.Height = msg.Height - headerHeight
 		m.tagViewPort.Width = msg.Width
 
-		if m.tagMenu != nil && len(m.commands) > 0 {
-			content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-			m.viewport.SetContent(content)
-			tagsContent := lipgloss.Style(boxedViewportStyle).Render(RenderTagMenu(m.tagMenu, m.currentTag, m.width))
-			m.tagViewPort.SetContent(tagsContent)
+		if m.tagMenu != nil && !m.showDetail {
+			m.scrollToSelection()
+			m.refreshTagBar()
 		}
 
 	case cheatsheetsLoadedMsg:
 		// Handle the loaded cheatsheet list
-		m.cheatsheets = []string(msg)
-		m.currentCheatsheet = 0
-		content := RenderCheatsheetList(m.cheatsheets, m.currentCheatsheet)
-		m.viewport.SetContent(content)
+		m.allCheatsheets = []sheetInfo(msg)
+		m.sheetSearchMode = false
+		m.sheetQuery = ""
+		m.applySheetFilter()
 
 	case cheatSheetLoadedMsg:
-		// Handle the loaded cheat sheet
-		m.cheatSheet = CheatSheet(msg)
-		m.commands = m.cheatSheet.Commands
-		m.tagMenu = UniqueTags(m.commands)
-		m.currentTag = 0
-		m.showCheatsheetSelector = false // Exit selector mode
-		// Update the view with the command list
-		logrus.Debugf("Window size set: width=%d, height=%d", m.width, m.height)
-		if len(m.commands) > 0 {
-			content := RenderCommandList(m.cheatSheet.Description, m.commands, m.currentCommand)
-			tagsContent := lipgloss.Style(boxedViewportStyle).Render(RenderTagMenu(m.tagMenu, m.currentTag, m.width))
-			m.tagViewPort.SetContent(tagsContent)
-			m.viewport.SetContent(content)
-		} else {
-			m.viewport.SetContent("No commands found in the cheat sheet.")
+		// Handle the loaded cheat sheet, looking its programs up on $PATH
+		// in the background
+		m.setCheatSheet(CheatSheet(msg))
+		m.openInitialCommand()
+		return m, checkBinaries(m.cheatSheet.Commands, m.binaries)
+
+	case annotationEditedMsg:
+		m.saveEditedAnnotation(msg)
+
+	case editorFinishedMsg:
+		m.reloadEdited(msg)
+		return m, checkBinaries(m.cheatSheet.Commands, m.binaries)
+
+	case binariesMsg:
+		if m.binaries == nil {
+			m.binaries = make(map[string]bool)
+		}
+		for binary, found := range msg {
+			m.binaries[binary] = found
+		}
+		m.markMissing()
+
+	case toolVersionsMsg:
+		if m.toolVersions == nil {
+			m.toolVersions = make(map[string]string)
+		}
+		for tool, version := range msg {
+			m.toolVersions[tool] = version
+		}
+		if m.versionMatchOnly {
+			m.applyFilters()
 		}
 
 	case errorMsg:
@@ -403,6 +828,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.viewport.SetContent(fmt.Sprintf("Error: %v", msg.err))
 	}
 
+	// The command list scrolls itself to follow the cursor, so only the
+	// detail view hands key presses on to the viewport
+	if _, ok := msg.(tea.KeyMsg); ok && !m.showDetail {
+		return m, nil
+	}
+
 	// Update viewport
 	m.viewport, cmd = m.viewport.Update(msg)
 	return m, cmd
@@ -413,6 +844,43 @@ func (m model) View() string {
 		return fmt.Sprintf("Error: %v\n\nPress q to quit.", m.err)
 	}
 
+	logrus.Debug(m.tagMenu)
+
+	// Build the view based on current mode
+	parts := m.headerParts()
+
+	// Add main viewport and help
+	parts = append(parts, m.mainView())
+	parts = append(parts, m.helpView())
+
+	return strings.Join(parts, "\n\n")
+}
+
+// mainView renders the main area: a window onto the command list, or the
+// viewport holding the selector or detail view
+func (m model) mainView() string {
+	if m.showCheatsheetSelector {
+		return m.selectorView()
+	}
+	if m.form != nil {
+		return RenderCommandForm(*m.form, m.viewport.Height, m.width)
+	}
+	if m.annotationEdit != nil && m.editErr != nil {
+		return RenderAnnotationError(m.annotationEdit.file, m.editErr, m.width)
+	}
+	if m.editErr != nil {
+		return RenderEditError(m.editPath, m.editErr, m.width)
+	}
+	if m.listVisible() {
+		return RenderCommandWindow(m.cheatSheet.Description, m.commands, m.currentCommand,
+			m.listOffset, m.viewport.Height, m.viewport.Width)
+	}
+	return m.viewport.View()
+}
+
+// headerParts returns the sections stacked above the main viewport. The mouse
+// handling relies on it to work out where each region landed on screen.
+func (m model) headerParts() []string {
 	// Handle cheatsheet selector mode
 	if m.showCheatsheetSelector {
 		header := lipgloss.NewStyle().
@@ -422,33 +890,17 @@ func (m model) View() string {
 			Padding(0, 1).
 			Render("Cheatsheet Selector")
 
-		helpText := "↑/↓: Navigate • Enter: Select • q: Quit"
-		helpView := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262")).
-			Render(helpText)
-
-		var parts []string
-		parts = append(parts, header)
-		parts = append(parts, m.viewport.View())
-		parts = append(parts, helpView)
-
-		return strings.Join(parts, "\n\n")
-	}
-
-	// Create help text based on current mode
-	var helpText string
-	if m.searchMode {
-		helpText = "Type to search • Enter: Apply • Esc: Cancel • q: Quit"
-	} else if m.searchActive {
-		helpText = "↑/↓: Navigate • Enter: View details • Esc: Clear search • o: Open cheatsheet • q: Quit"
-	} else {
-		helpText = "↑/↓: Navigate • ←/→: Tag Filter • /: Search • Enter: View details • o: Open cheatsheet • Esc: Back • q: Quit"
+		parts := []string{header}
+		if m.sheetSearchMode {
+			parts = append(parts, RenderSearchBar(m.sheetQuery, m.width))
+		} else if m.sheetQuery != "" {
+			searchIndicator := filterStatusStyle.
+				Render(fmt.Sprintf("Search: %s (%d of %d cheatsheets)", m.sheetQuery, len(m.cheatsheets), len(m.allCheatsheets)))
+			parts = append(parts, searchIndicator)
+		}
+		return parts
 	}
 
-	helpView := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Render(helpText)
-
 	// Create a header
 	var header string
 	if m.showDetail && len(m.commands) > 0 {
@@ -467,29 +919,80 @@ func (m model) View() string {
 			Render(m.cheatSheet.Title)
 	}
 
-	logrus.Debug(m.tagMenu)
-
-	// Build the view based on current mode
 	var parts []string
 	parts = append(parts, header)
 
-	// Show search bar when in search mode, or search indicator when search is active
+	// Show search bar when in search mode, otherwise the tag bar, whose
+	// status line also reports an applied search
 	if m.searchMode {
 		searchBar := RenderSearchBar(m.searchQuery, m.width)
 		parts = append(parts, searchBar)
-	} else if m.searchActive {
-		searchIndicator := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#5AF78E")).
-			Render(fmt.Sprintf("🔍 Search: %s (%d results)", m.searchQuery, len(m.commands)))
-		parts = append(parts, searchIndicator)
 	} else {
-		// Show tag viewport only when not in search mode or active
 		parts = append(parts, m.tagViewPort.View())
 	}
 
-	// Add main viewport and help
-	parts = append(parts, m.viewport.View())
-	parts = append(parts, helpView)
+	return parts
+}
 
-	return strings.Join(parts, "\n\n")
+// showsDeprecated reports whether the selected command is deprecated in
+// favour of another
+func (m model) showsDeprecated() bool {
+	if len(m.commands) == 0 {
+		return false
+	}
+	d := m.commands[m.currentCommand].Deprecated
+	return d != nil && d.Replacement != ""
+}
+
+// truncatesOutput reports whether the selected command has an example output
+// too long to show in full unless expanded
+func (m model) truncatesOutput() bool {
+	if len(m.commands) == 0 {
+		return false
+	}
+	for _, ex := range m.commands[m.currentCommand].Examples {
+		if outputLines(ex.Output) > maxOutputLines {
+			return true
+		}
+	}
+	return false
+}
+
+// helpView renders the key help line for the current mode
+func (m model) helpView() string {
+	var helpText string
+	if m.showCheatsheetSelector && m.sheetSearchMode {
+		helpText = "Type to search titles, categories and descriptions • Enter: Apply • Esc: Cancel"
+	} else if m.showCheatsheetSelector {
+		helpText = fmt.Sprintf("↑/↓: Navigate • Enter: Select/Fold • ←/→: Collapse/Expand • v: View (%s) • /: Search • Esc: Clear search • Double-click: Open • q: Quit", m.selectorGrouping)
+	} else if m.form != nil {
+		helpText = "Tab/↑/↓: Move • Enter: Next/Add • Tab: Complete • ←/→: Complexity • Ctrl+D: Remove row • Ctrl+S: Save • Esc: Cancel"
+	} else if m.editErr != nil {
+		helpText = "e: Edit again • Esc: Keep the previous version • q: Quit"
+	} else if m.searchMode {
+		helpText = "Type to search • Enter: Apply • Esc: Cancel • q: Quit"
+	} else if m.jumpInput != "" {
+		helpText = fmt.Sprintf("Go to command %s • Enter: Jump • Esc: Cancel", m.jumpInput)
+	} else if m.showDetail && (m.showsDeprecated() || m.truncatesOutput()) {
+		helpText = "↑/↓: Scroll • "
+		if m.truncatesOutput() {
+			if m.expandOutput {
+				helpText += "O: Collapse output • "
+			} else {
+				helpText += "O: Expand output • "
+			}
+		}
+		if m.showsDeprecated() {
+			helpText += "r: Go to replacement • "
+		}
+		helpText += "e/f: Edit • n: Annotate • Esc: Back • q: Quit"
+	} else if m.searchActive {
+		helpText = "↑/↓: Navigate • ←/→: Tags • Space: Toggle tag • x: Exclude tag • m: Any/All • c/C: Complexity • s: Sort • Enter: View details • Esc: Clear search • o: Open cheatsheet • q: Quit"
+	} else {
+		helpText = "↑/↓: Navigate • PgUp/PgDn/g/G: Jump • 0-9: Go to # • ←/→: Tags • Space: Toggle tag • x: Exclude tag • m: Any/All • c/C: Complexity • s: Sort • p/V/i: Platform/Version/Installed • /: Search • Enter: View details • e/f: Edit • n: Annotate • a: Add • o: Open cheatsheet • Esc: Back • q: Quit"
+	}
+
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#626262")).
+		Render(helpText)
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manSection is the section of the manual sheets are exported to, the one
+// for overviews and conventions
+const manSection = "7"
+
+// manEscape escapes text for roff: backslashes and hyphens, which roff would
+// otherwise read as escapes and break or typeset as dashes, and periods and
+// apostrophes starting a line, which it would read as requests
+func manEscape(text string) string {
+	text = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// manQuote quotes text as a single argument of a roff request
+func manQuote(text string) string {
+	return `"` + strings.ReplaceAll(manEscape(text), `"`, `\(dq`) + `"`
+}
+
+// manName returns the name of the man page of a sheet: "cheatcheat-" and the
+// sheet's file name, so kubectl.yaml is read with "man cheatcheat-kubectl"
+func manName(sheet CheatSheet) string {
+	base := filepath.Base(sheet.Path)
+	name := "cheatcheat-" + strings.TrimSuffix(base, filepath.Ext(base))
+	if sheet.Document > 0 {
+		name += fmt.Sprintf("-%d", sheet.Document+1)
+	}
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// writeManCode writes a block of code, kept as it is
+func writeManCode(b *bytes.Buffer, code string) {
+	b.WriteString(".PP\n.RS 4\n.nf\n")
+	b.WriteString(manEscape(strings.TrimRight(code, "\n")))
+	b.WriteString("\n.fi\n.RE\n")
+}
+
+// exportMan renders sheet as a man page named name: NAME and DESCRIPTION
+// from the sheet, and a subsection of COMMANDS for each command
+func exportMan(sheet CheatSheet, name string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, ".TH %s %s \"\" cheatcheat Cheatsheets\n", manQuote(strings.ToUpper(name)), manSection)
+	b.WriteString(".SH NAME\n" + manEscape(name))
+	if sheet.Title != "" {
+		b.WriteString(` \- ` + manEscape(sheet.Title))
+	}
+	b.WriteString("\n")
+	if sheet.Description != "" {
+		fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", manEscape(sheet.Description))
+	}
+	if len(sheet.Commands) == 0 {
+		return b.Bytes()
+	}
+
+	b.WriteString(".SH COMMANDS\n")
+	for _, cmd := range sheet.Commands {
+		fmt.Fprintf(&b, ".SS %s\n", manQuote(cmd.Name))
+		if cmd.ShortDesc != "" {
+			fmt.Fprintf(&b, "%s\n", manEscape(cmd.ShortDesc))
+		}
+		if cmd.Syntax != "" {
+			writeManCode(&b, cmd.Syntax)
+		}
+		if len(cmd.Options) > 0 {
+			b.WriteString(".PP\n.B Options:\n")
+			for _, opt := range cmd.Options {
+				fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", manQuote(opt.Flag), manEscape(opt.Description))
+			}
+		}
+		if len(cmd.Examples) > 0 {
+			b.WriteString(".PP\n.B Examples:\n")
+			for _, ex := range cmd.Examples {
+				if ex.Description != "" {
+					fmt.Fprintf(&b, ".PP\n%s\n", manEscape(ex.Description))
+				}
+				code := "$ " + ex.Code
+				if ex.Output != "" {
+					code += "\n" + ex.Output
+				}
+				writeManCode(&b, code)
+			}
+		}
+		if len(cmd.Notes) > 0 {
+			b.WriteString(".PP\n.B Notes:\n")
+			for _, note := range cmd.Notes {
+				fmt.Fprintf(&b, ".IP \\(bu 2\n%s\n", manEscape(note))
+			}
+		}
+		if len(cmd.Related) > 0 {
+			fmt.Fprintf(&b, ".PP\nRelated: %s\n", manEscape(strings.Join(cmd.Related, ", ")))
+		}
+	}
+	return b.Bytes()
+}
+
+// runExportMan implements "cheatcheat export man": a man page for each
+// sheet, written to a directory that can go on $MANPATH as man7
+func runExportMan(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export man", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", ".", "directory to write the pages to")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat export man [--out dir] [paths...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	sheets, err := loadExportSheets(flags.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	written := make(map[string]bool)
+	for _, sheet := range sheets {
+		// Sheets of the same file name in different directories
+		name := manName(sheet)
+		for i := 2; written[name]; i++ {
+			name = fmt.Sprintf("%s-%d", manName(sheet), i)
+		}
+		written[name] = true
+
+		path := filepath.Join(*out, name+"."+manSection)
+		if err := os.WriteFile(path, exportMan(sheet, name), 0644); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintln(stdout, path)
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExportManGolden renders testdata/man/sheet.yaml and compares the page
+// with cheatcheat-sheet.7 next to it. Run with -update to rewrite it after an
+// intended change.
+func TestExportManGolden(t *testing.T) {
+	sheets, err := LoadCheatSheets(filepath.Join("testdata", "man", "sheet.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := manName(sheets[0])
+	if name != "cheatcheat-sheet" {
+		t.Errorf("got page name %q", name)
+	}
+	got := exportMan(sheets[0], name)
+
+	golden := filepath.Join("testdata", "man", name+".7")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("page doesn't match %s:\n%s", golden, got)
+	}
+}
+
+func TestManEscape(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"grep -i", `grep \-i`},
+		{`a\b`, `a\eb`},
+		{".hidden\n'quoted'\nmid.dle", "\\&.hidden\n\\&'quoted'\nmid.dle"},
+	}
+	for _, tt := range tests {
+		if got := manEscape(tt.text); got != tt.want {
+			t.Errorf("manEscape(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+	if got := manQuote(`say "hi"`); got != `"say \(dqhi\(dq"` {
+		t.Errorf("manQuote() = %s", got)
+	}
+}
+
+func TestRunExportMan(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		os.WriteFile(filepath.Join(dir, sub, "Git Tools.yaml"), []byte("title: Git "+sub+"\ncommands: []\n"), 0o644)
+	}
+	out := filepath.Join(t.TempDir(), "man7")
+
+	var stdout, stderr bytes.Buffer
+	if status := runExport([]string{"man", "--out", out, dir}, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected exit status 0, got %d (%s)", status, stderr.String())
+	}
+	want := filepath.Join(out, "cheatcheat-git-tools.7") + "\n" + filepath.Join(out, "cheatcheat-git-tools-2.7") + "\n"
+	if got := stdout.String(); got != want {
+		t.Errorf("got pages\n%s\nwant\n%s", got, want)
+	}
+	page, err := os.ReadFile(filepath.Join(out, "cheatcheat-git-tools-2.7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(page), ".TH \"CHEATCHEAT\\-GIT\\-TOOLS\\-2\" 7 \"\" cheatcheat Cheatsheets\n.SH NAME\ncheatcheat\\-git\\-tools\\-2 \\- Git b\n") {
+		t.Errorf("got page:\n%s", page)
+	}
+}
@@ -0,0 +1,772 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Markdown sheet keeps the title, description, category and the extends,
+// include and drop keys in YAML front matter and gives each command a "##"
+// heading:
+//
+//	## git commit
+//
+//	Record changes to the repository
+//
+//	Tags: basics, snapshot
+//	Complexity: beginner
+//
+//	```
+//	git commit [options]
+//	```
+//
+//	### Examples
+//
+//	Commit with a message
+//
+//	```
+//	git commit -m "Fix typo"
+//	```
+//
+//	```output
+//	[main 1a2b3c4] Fix typo
+//	```
+//
+//	### Options
+//
+//	| Flag | Description |
+//	| --- | --- |
+//	| `-m` | Use the given message |
+//
+//	### Notes
+//
+//	- One note per list item
+//
+//	### Related
+//
+//	- git add
+//
+// The paragraph under the heading is the short description and the code block
+// before any section is the syntax. A code block marked "output", or
+// "output exitCode=N", right after the code of an example holds what it
+// prints, and one marked "verify" holds the YAML of its verify key.
+
+// frontMatterDelimiter opens and closes the YAML front matter of Markdown
+const frontMatterDelimiter = "---"
+
+// splitFrontMatter separates the YAML front matter of a Markdown file from
+// its body. ok is false when the file doesn't start with front matter.
+func splitFrontMatter(data []byte) (frontMatter, body []byte, ok bool) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelimiter+"\n") {
+		return nil, data, false
+	}
+	rest := text[len(frontMatterDelimiter)+1:]
+	end := strings.Index("\n"+rest, "\n"+frontMatterDelimiter+"\n")
+	if end < 0 {
+		if !strings.HasSuffix(rest, "\n"+frontMatterDelimiter) && rest != frontMatterDelimiter {
+			return nil, data, false
+		}
+		end = len(rest) - len(frontMatterDelimiter)
+		return []byte(rest[:end]), nil, true
+	}
+	return []byte(rest[:end]), []byte(rest[end+len(frontMatterDelimiter)+1:]), true
+}
+
+// hasFrontMatter reports whether a Markdown file starts with front matter,
+// which tells sheets apart from READMEs and other notes next to them. Sheets
+// without it can still be included by others.
+func hasFrontMatter(data []byte) bool {
+	_, _, ok := splitFrontMatter(data)
+	return ok
+}
+
+// mdBlockKind tells the kinds of Markdown blocks apart
+type mdBlockKind int
+
+const (
+	mdHeading mdBlockKind = iota
+	mdParagraph
+	mdFence
+	mdList
+	mdTable
+)
+
+// mdItem is a line of a paragraph, an item of a list or a row of a table
+type mdItem struct {
+	line int
+	text string
+}
+
+// mdBlock is one block of a Markdown body
+type mdBlock struct {
+	kind  mdBlockKind
+	line  int      // 1-based line the block starts on
+	level int      // number of #s of a heading
+	text  string   // text of a heading or content of a code block
+	info  string   // what follows the opening fence of a code block, like "output"
+	items []mdItem // lines of a paragraph, items of a list or rows of a table
+}
+
+// joined returns the lines of a paragraph as one line of text
+func (b mdBlock) joined() string {
+	var parts []string
+	for _, item := range b.items {
+		parts = append(parts, item.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	mdHeadingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	mdFencePattern   = regexp.MustCompile("^(`{3,}|~{3,})")
+	mdListPattern    = regexp.MustCompile(`^(?:[-*+]|\d+[.)])[ \t]+(.*)$`)
+)
+
+// lineErrorf builds an error pointing at a line of a sheet, worded like the
+// errors of yaml.v3 so errorLine finds the line
+func lineErrorf(line int, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// startsBlock reports whether line opens a block that interrupts a paragraph
+// or a list item
+func startsBlock(line string) bool {
+	return mdHeadingPattern.MatchString(line) || mdFencePattern.MatchString(line) ||
+		mdListPattern.MatchString(line) || strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+// markdownBlocks splits the lines of a Markdown body into blocks. first is
+// the line number of lines[0].
+func markdownBlocks(lines []string, first int) ([]mdBlock, error) {
+	var blocks []mdBlock
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		at := first + i
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case mdFencePattern.MatchString(line):
+			fence := mdFencePattern.FindString(line)
+			end := i + 1
+			for end < len(lines) && !closesFence(lines[end], fence) {
+				end++
+			}
+			if end == len(lines) {
+				return nil, lineErrorf(at, "code block is never closed")
+			}
+			info := strings.TrimSpace(strings.TrimPrefix(line, fence))
+			blocks = append(blocks, mdBlock{kind: mdFence, line: at, info: info, text: strings.Join(lines[i+1:end], "\n")})
+			i = end + 1
+
+		case mdHeadingPattern.MatchString(line):
+			match := mdHeadingPattern.FindStringSubmatch(line)
+			blocks = append(blocks, mdBlock{kind: mdHeading, line: at, level: len(match[1]), text: match[2]})
+			i++
+
+		case strings.HasPrefix(strings.TrimSpace(line), "|"):
+			block := mdBlock{kind: mdTable, line: at}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				block.items = append(block.items, mdItem{first + i, strings.TrimSpace(lines[i])})
+			}
+			blocks = append(blocks, block)
+
+		case mdListPattern.MatchString(line):
+			block := mdBlock{kind: mdList, line: at}
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				if match := mdListPattern.FindStringSubmatch(lines[i]); match != nil {
+					block.items = append(block.items, mdItem{first + i, strings.TrimSpace(match[1])})
+				} else if startsBlock(lines[i]) {
+					break
+				} else {
+					// A continuation line of the item above
+					last := &block.items[len(block.items)-1]
+					last.text += " " + strings.TrimSpace(lines[i])
+				}
+			}
+			blocks = append(blocks, block)
+
+		default:
+			block := mdBlock{kind: mdParagraph, line: at}
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				if len(block.items) > 0 && startsBlock(lines[i]) {
+					break
+				}
+				block.items = append(block.items, mdItem{first + i, strings.TrimSpace(lines[i])})
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+// closesFence reports whether line closes the code block opened by fence
+func closesFence(line, fence string) bool {
+	line = strings.TrimRight(line, " \t")
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// tableCells splits a table row into its cells, unescaping \| and turning
+// <br> back into line breaks
+func tableCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	cells = append(cells, cell.String())
+	for i := range cells {
+		cells[i] = strings.ReplaceAll(strings.TrimSpace(cells[i]), "<br>", "\n")
+	}
+	return cells
+}
+
+// isDelimiterRow reports whether the cells are the |---|---| row under a
+// table header
+func isDelimiterRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.Trim(cell, ":-") != "" || !strings.Contains(cell, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// unquoteCode strips the backticks around text that is a single code span
+func unquoteCode(text string) string {
+	ticks := len(text) - len(strings.TrimLeft(text, "`"))
+	if ticks == 0 || len(text) < 2*ticks || strings.TrimRight(text, "`") != text[:len(text)-ticks] {
+		return text
+	}
+	inner := text[ticks : len(text)-ticks]
+	if strings.HasPrefix(inner, " ") && strings.HasSuffix(inner, " ") && strings.TrimSpace(inner) != "" {
+		inner = inner[1 : len(inner)-1]
+	}
+	return inner
+}
+
+// commandMetadata maps the "Key: value" lines under a command heading onto
+// the command
+var commandMetadata = map[string]func(cmd *Command, value string) error{
+	"tags": func(cmd *Command, value string) error {
+		cmd.Tags = append(cmd.Tags, metadataList(value)...)
+		return nil
+	},
+	"aliases": func(cmd *Command, value string) error {
+		cmd.Aliases = append(cmd.Aliases, metadataList(value)...)
+		return nil
+	},
+	"keywords": func(cmd *Command, value string) error {
+		cmd.Keywords = append(cmd.Keywords, metadataList(value)...)
+		return nil
+	},
+	"complexity": func(cmd *Command, value string) error {
+		cmd.Complexity = value
+		return nil
+	},
+	"platforms": func(cmd *Command, value string) error {
+		cmd.Platforms = append(cmd.Platforms, metadataList(value)...)
+		return nil
+	},
+	"min version": func(cmd *Command, value string) error {
+		cmd.MinVersion = value
+		return nil
+	},
+	"max version": func(cmd *Command, value string) error {
+		cmd.MaxVersion = value
+		return nil
+	},
+	// Deprecated: since 2.23; use git switch
+	"deprecated": func(cmd *Command, value string) error {
+		cmd.Deprecated = &Deprecation{}
+		for _, part := range strings.Split(value, ";") {
+			part = strings.TrimSpace(part)
+			switch {
+			case part == "":
+			case strings.HasPrefix(part, "since "):
+				cmd.Deprecated.Since = strings.TrimSpace(strings.TrimPrefix(part, "since "))
+			case strings.HasPrefix(part, "use "):
+				cmd.Deprecated.Replacement = unquoteCode(strings.TrimSpace(strings.TrimPrefix(part, "use ")))
+			default:
+				return fmt.Errorf("expected \"since <version>\" or \"use <command>\", got %q", part)
+			}
+		}
+		return nil
+	},
+}
+
+// metadataList splits the comma separated values of a metadata line
+func metadataList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = unquoteCode(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// metadataLine splits a "Key: value" line whose key is in commandMetadata
+func metadataLine(text string) (key, value string, ok bool) {
+	key, value, found := strings.Cut(text, ":")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if _, known := commandMetadata[key]; !found || !known {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(value), true
+}
+
+// markdownParser reads the blocks of a Markdown sheet into a CheatSheet
+type markdownParser struct {
+	sheet   CheatSheet
+	cmd     *Command // command being read, nil before the first heading
+	section string   // "###" section being read, "" under the command heading
+	syntax  bool     // whether the command's syntax block was read
+	example *Example // example whose description awaits its code block
+}
+
+// markdownSections reads the blocks of each "###" section of a command
+var markdownSections = map[string]func(p *markdownParser, b mdBlock) error{
+	"examples": (*markdownParser).readExample,
+	"options":  (*markdownParser).readOptions,
+	"notes":    (*markdownParser).readNotes,
+	"related":  (*markdownParser).readRelated,
+}
+
+// decodeMarkdownSheets decodes a Markdown sheet. Errors name the line they
+// are on.
+func decodeMarkdownSheets(data []byte) ([]CheatSheet, error) {
+	var p markdownParser
+	body, first := string(data), 1
+	if frontMatter, rest, ok := splitFrontMatter(data); ok {
+		if err := yaml.Unmarshal(frontMatter, &p.sheet); err != nil {
+			// Count lines from the top of the file, not of the front matter
+			return nil, fmt.Errorf("front matter: %s", shiftErrorLines(err, 1))
+		}
+		body, first = string(rest), bytes.Count(frontMatter, []byte("\n"))+3
+	}
+
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	blocks, err := markdownBlocks(lines, first)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range blocks {
+		if err := p.read(b); err != nil {
+			return nil, err
+		}
+	}
+	p.endCommand()
+	return []CheatSheet{p.sheet}, nil
+}
+
+// shiftErrorLines adds offset to the line numbers in a yaml.v3 error
+func shiftErrorLines(err error, offset int) string {
+	return errorLinePattern.ReplaceAllStringFunc(err.Error(), func(match string) string {
+		var line int
+		fmt.Sscanf(match, "line %d", &line)
+		return fmt.Sprintf("line %d", line+offset)
+	})
+}
+
+// read takes in the next block of the body
+func (p *markdownParser) read(b mdBlock) error {
+	if b.kind == mdHeading {
+		return p.readHeading(b)
+	}
+	switch {
+	case p.cmd == nil:
+		if b.kind != mdParagraph {
+			return lineErrorf(b.line, "expected a ## heading naming a command")
+		}
+		// Text ahead of the first command describes the sheet
+		if p.sheet.Description != "" {
+			p.sheet.Description += "\n\n"
+		}
+		p.sheet.Description += b.joined()
+		return nil
+	case p.section == "":
+		return p.readIntro(b)
+	default:
+		return markdownSections[p.section](p, b)
+	}
+}
+
+func (p *markdownParser) readHeading(b mdBlock) error {
+	switch b.level {
+	case 1:
+		if p.cmd != nil {
+			return lineErrorf(b.line, "a # heading titles the sheet and goes above the first command")
+		}
+		if p.sheet.Title == "" {
+			p.sheet.Title = b.text
+		}
+	case 2:
+		p.endCommand()
+		name := unquoteCode(b.text)
+		if name == "" {
+			return lineErrorf(b.line, "command heading without a name")
+		}
+		p.cmd = &Command{Name: name}
+	case 3:
+		if p.cmd == nil {
+			return lineErrorf(b.line, "section %q is outside of any command", b.text)
+		}
+		section := strings.ToLower(b.text)
+		if _, ok := markdownSections[section]; !ok {
+			return lineErrorf(b.line, "unknown section %q (want Examples, Options, Notes or Related)", b.text)
+		}
+		p.endExample()
+		p.section = section
+	default:
+		return lineErrorf(b.line, "unexpected level %d heading; commands use ## and their sections ###", b.level)
+	}
+	return nil
+}
+
+// readIntro reads the blocks between a command heading and its first section:
+// the short description, the metadata lines and the syntax block
+func (p *markdownParser) readIntro(b mdBlock) error {
+	switch b.kind {
+	case mdParagraph:
+		if _, _, ok := metadataLine(b.items[0].text); ok {
+			for _, item := range b.items {
+				key, value, ok := metadataLine(item.text)
+				if !ok {
+					return lineErrorf(item.line, "expected a line like Tags:, Aliases:, Keywords:, Complexity:, Platforms:, Min version:, Max version: or Deprecated:")
+				}
+				if err := commandMetadata[key](p.cmd, value); err != nil {
+					return lineErrorf(item.line, "%s", err)
+				}
+			}
+			return nil
+		}
+		if p.cmd.ShortDesc != "" {
+			p.cmd.ShortDesc += "\n\n"
+		}
+		p.cmd.ShortDesc += b.joined()
+	case mdFence:
+		if p.syntax {
+			return lineErrorf(b.line, "command %q already has a syntax block; examples go under ### Examples", p.cmd.Name)
+		}
+		p.cmd.Syntax = b.text
+		p.syntax = true
+	default:
+		return lineErrorf(b.line, "expected a description, Tags:/Complexity: lines or a syntax block under command %q", p.cmd.Name)
+	}
+	return nil
+}
+
+// readExample reads an example: a paragraph describing it, then its code
+func (p *markdownParser) readExample(b mdBlock) error {
+	switch b.kind {
+	case mdParagraph:
+		if p.example == nil {
+			p.example = &Example{}
+		} else {
+			p.example.Description += "\n\n"
+		}
+		p.example.Description += b.joined()
+	case mdFence:
+		if fields := strings.Fields(b.info); len(fields) > 0 && fields[0] == "output" {
+			return p.readOutput(b, fields[1:])
+		}
+		if b.info == "verify" {
+			return p.readVerify(b)
+		}
+		if p.example == nil {
+			p.example = &Example{}
+		}
+		p.example.Code = b.text
+		p.endExample()
+	default:
+		return lineErrorf(b.line, "expected a description or a code block in the examples of %q", p.cmd.Name)
+	}
+	return nil
+}
+
+// lastExample returns the example whose code was just read, for the kind of
+// block b (output or verify) to add to
+func (p *markdownParser) lastExample(b mdBlock, kind string) (*Example, error) {
+	if p.example != nil || len(p.cmd.Examples) == 0 {
+		return nil, lineErrorf(b.line, "%s block must follow the code of an example", kind)
+	}
+	return &p.cmd.Examples[len(p.cmd.Examples)-1], nil
+}
+
+// readOutput reads the output block of the example just read, with the
+// attributes that followed "output" in its fence
+func (p *markdownParser) readOutput(b mdBlock, attrs []string) error {
+	ex, err := p.lastExample(b, "an output")
+	if err != nil {
+		return err
+	}
+	ex.Output = b.text
+	for _, attr := range attrs {
+		value, ok := strings.CutPrefix(attr, "exitCode=")
+		code, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			return lineErrorf(b.line, "expected exitCode=N after output, got %q", attr)
+		}
+		ex.ExitCode = code
+	}
+	return nil
+}
+
+// readVerify reads the verify block of the example just read
+func (p *markdownParser) readVerify(b mdBlock) error {
+	ex, err := p.lastExample(b, "a verify")
+	if err != nil {
+		return err
+	}
+	ex.Verify = &Verification{}
+	if err := yaml.Unmarshal([]byte(b.text), ex.Verify); err != nil {
+		// Count lines from the top of the file, not of the block
+		return errors.New(shiftErrorLines(err, b.line))
+	}
+	return nil
+}
+
+// readOptions reads the Flag | Description table of a command
+func (p *markdownParser) readOptions(b mdBlock) error {
+	if b.kind != mdTable {
+		return lineErrorf(b.line, "expected a | Flag | Description | table in the options of %q", p.cmd.Name)
+	}
+	for i, row := range b.items {
+		cells := tableCells(row.text)
+		if i == 1 && isDelimiterRow(cells) {
+			continue
+		}
+		if len(cells) != 2 {
+			return lineErrorf(row.line, "expected 2 columns, flag and description, got %d", len(cells))
+		}
+		if i == 0 {
+			// The header row
+			continue
+		}
+		p.cmd.Options = append(p.cmd.Options, Option{Flag: unquoteCode(cells[0]), Description: cells[1]})
+	}
+	return nil
+}
+
+// readNotes reads notes, one per list item or paragraph
+func (p *markdownParser) readNotes(b mdBlock) error {
+	switch b.kind {
+	case mdList:
+		for _, item := range b.items {
+			p.cmd.Notes = append(p.cmd.Notes, item.text)
+		}
+	case mdParagraph:
+		p.cmd.Notes = append(p.cmd.Notes, b.joined())
+	default:
+		return lineErrorf(b.line, "expected a list in the notes of %q", p.cmd.Name)
+	}
+	return nil
+}
+
+// readRelated reads the list of related commands
+func (p *markdownParser) readRelated(b mdBlock) error {
+	if b.kind != mdList {
+		return lineErrorf(b.line, "expected a list in the related commands of %q", p.cmd.Name)
+	}
+	for _, item := range b.items {
+		p.cmd.Related = append(p.cmd.Related, unquoteCode(item.text))
+	}
+	return nil
+}
+
+// endExample adds the example being read to the command
+func (p *markdownParser) endExample() {
+	if p.example != nil {
+		p.cmd.Examples = append(p.cmd.Examples, *p.example)
+		p.example = nil
+	}
+}
+
+// endCommand adds the command being read to the sheet
+func (p *markdownParser) endCommand() {
+	if p.cmd == nil {
+		return
+	}
+	p.endExample()
+	p.sheet.Commands = append(p.sheet.Commands, *p.cmd)
+	p.cmd, p.section, p.syntax = nil, "", false
+}
+
+// markdownCommandLine returns the line of the heading of the named command
+// in a Markdown sheet, or 0 when it can't be found
+func markdownCommandLine(data []byte, _ int, name string) int {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	blocks, err := markdownBlocks(lines, 1)
+	if err != nil {
+		return 0
+	}
+	for _, b := range blocks {
+		if b.kind == mdHeading && b.level == 2 && unquoteCode(b.text) == name {
+			return b.line
+		}
+	}
+	return 0
+}
+
+// encodeMarkdownSheets writes a sheet as Markdown
+func encodeMarkdownSheets(sheets []CheatSheet) ([]byte, error) {
+	if len(sheets) != 1 {
+		return nil, fmt.Errorf("a Markdown file holds one sheet, got %d", len(sheets))
+	}
+	sheet := sheets[0]
+
+	var b bytes.Buffer
+	header := sheet
+	header.Commands = nil
+	if !reflect.DeepEqual(header, CheatSheet{Path: sheet.Path, Document: sheet.Document}) {
+		// Everything but the commands, which the body holds
+		data, err := yaml.Marshal(header)
+		if err != nil {
+			return nil, err
+		}
+		frontMatter, err := formatSheet(bytes.Replace(data, []byte("commands: []\n"), nil, 1))
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(frontMatterDelimiter + "\n")
+		b.Write(frontMatter)
+		b.WriteString(frontMatterDelimiter + "\n")
+	}
+
+	for _, cmd := range sheet.Commands {
+		writeMarkdownCommand(&b, cmd)
+	}
+	return b.Bytes(), nil
+}
+
+// writeMarkdownCommand writes one command as a "##" section
+func writeMarkdownCommand(b *bytes.Buffer, cmd Command) {
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(b, "## %s\n", cmd.Name)
+	if cmd.ShortDesc != "" {
+		fmt.Fprintf(b, "\n%s\n", cmd.ShortDesc)
+	}
+	var metadata []string
+	if len(cmd.Tags) > 0 {
+		metadata = append(metadata, "Tags: "+strings.Join(cmd.Tags, ", "))
+	}
+	if len(cmd.Aliases) > 0 {
+		metadata = append(metadata, "Aliases: "+strings.Join(cmd.Aliases, ", "))
+	}
+	if len(cmd.Keywords) > 0 {
+		metadata = append(metadata, "Keywords: "+strings.Join(cmd.Keywords, ", "))
+	}
+	if cmd.Complexity != "" {
+		metadata = append(metadata, "Complexity: "+cmd.Complexity)
+	}
+	if len(cmd.Platforms) > 0 {
+		metadata = append(metadata, "Platforms: "+strings.Join(cmd.Platforms, ", "))
+	}
+	if cmd.MinVersion != "" {
+		metadata = append(metadata, "Min version: "+cmd.MinVersion)
+	}
+	if cmd.MaxVersion != "" {
+		metadata = append(metadata, "Max version: "+cmd.MaxVersion)
+	}
+	if d := cmd.Deprecated; d != nil {
+		var parts []string
+		if d.Since != "" {
+			parts = append(parts, "since "+d.Since)
+		}
+		if d.Replacement != "" {
+			parts = append(parts, "use "+d.Replacement)
+		}
+		metadata = append(metadata, strings.TrimSpace("Deprecated: "+strings.Join(parts, "; ")))
+	}
+	if len(metadata) > 0 {
+		fmt.Fprintf(b, "\n%s\n", strings.Join(metadata, "\n"))
+	}
+	if cmd.Syntax != "" {
+		b.WriteString("\n")
+		writeFence(b, "", cmd.Syntax)
+	}
+
+	if len(cmd.Examples) > 0 {
+		b.WriteString("\n### Examples\n")
+		for _, ex := range cmd.Examples {
+			if ex.Description != "" {
+				fmt.Fprintf(b, "\n%s\n", ex.Description)
+			}
+			if ex.Code != "" {
+				b.WriteString("\n")
+				writeFence(b, "", ex.Code)
+			}
+			if ex.Output != "" || ex.ExitCode != 0 {
+				info := "output"
+				if ex.ExitCode != 0 {
+					info += fmt.Sprintf(" exitCode=%d", ex.ExitCode)
+				}
+				b.WriteString("\n")
+				writeFence(b, info, ex.Output)
+			}
+			if ex.Verify != nil {
+				data, _ := yaml.Marshal(ex.Verify) // strings and an int always marshal
+				b.WriteString("\n")
+				writeFence(b, "verify", strings.TrimSuffix(string(data), "\n"))
+			}
+		}
+	}
+	if len(cmd.Options) > 0 {
+		b.WriteString("\n### Options\n\n| Flag | Description |\n| --- | --- |\n")
+		for _, opt := range cmd.Options {
+			flag := tableCell(opt.Flag)
+			if flag != "" && !strings.Contains(flag, "`") {
+				flag = "`" + flag + "`"
+			}
+			fmt.Fprintf(b, "| %s | %s |\n", flag, tableCell(opt.Description))
+		}
+	}
+	if len(cmd.Notes) > 0 {
+		b.WriteString("\n### Notes\n\n")
+		for _, note := range cmd.Notes {
+			fmt.Fprintf(b, "- %s\n", note)
+		}
+	}
+	if len(cmd.Related) > 0 {
+		b.WriteString("\n### Related\n\n")
+		for _, related := range cmd.Related {
+			fmt.Fprintf(b, "- %s\n", related)
+		}
+	}
+}
+
+// writeFence writes code as a fenced block, with a fence longer than any run
+// of backticks in it
+func writeFence(b *bytes.Buffer, info, code string) {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, info, code, fence)
+}
+
+// tableCell escapes text for a table cell
+func tableCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", "<br>")
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMarkdownSheet(t *testing.T) {
+	data, err := os.ReadFile("testdata/markdown/sheet.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets, err := decodeMarkdownSheets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := CheatSheet{
+		Title:       "Archives",
+		Description: "Packing and unpacking files",
+		Category:    "Linux",
+		Commands: []Command{
+			{
+				Name:       "tar create",
+				ShortDesc:  "Create a tar archive",
+				Syntax:     "tar -cf <archive> <files>",
+				Tags:       []string{"archive", "backup"},
+				Complexity: "beginner",
+				Examples: []Example{
+					{Code: "tar -cf site.tar public/", Description: "Archive a directory"},
+					{Code: "tar -czf site.tgz public/"},
+				},
+				Notes: []string{"Order of flags matters for -f", "Use ```` ``` ```` fences freely"},
+				Options: []Option{
+					{Flag: "-c", Description: "Create a new archive"},
+					{Flag: "-z", Description: "Filter through gzip"},
+					{Flag: "-f", Description: "Use archive file \nor device"},
+					{Flag: "a|b", Description: "Either one"},
+				},
+				Related: []string{"tar extract"},
+			},
+			{Name: "zip", Syntax: "zip -r <archive> ```files```"},
+		},
+	}
+	if !reflect.DeepEqual(sheets, []CheatSheet{want}) {
+		t.Errorf("Expected %+v, got %+v", want, sheets)
+	}
+}
+
+func TestEncodeMarkdownSheet(t *testing.T) {
+	data, err := os.ReadFile("testdata/markdown/sheet.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets, err := decodeMarkdownSheets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := encodeMarkdownSheets(sheets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		removed, added := diffLines(string(data), string(got))
+		t.Errorf("Expected the fixture written back unchanged, got -%q +%q", removed, added)
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	paths, err := DiscoverCheatsheets("cheatsheets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			sheets, err := LoadCheatSheets(filepath.Join("cheatsheets", path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range sheets {
+				sheets[i].Path = ""
+			}
+			data, err := encodeMarkdownSheets(sheets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			back, err := decodeMarkdownSheets(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range back {
+				validateSheet(&back[i])
+			}
+			// Compare as JSON, which doesn't tell empty lists from missing ones
+			got, _ := encodeJSONSheets(back)
+			want, _ := encodeJSONSheets(sheets)
+			if !bytes.Equal(got, want) {
+				removed, added := diffLines(string(want), string(got))
+				t.Errorf("Expected the same sheet back, got -%q +%q", removed, added)
+			}
+		})
+	}
+}
+
+func TestMarkdownErrors(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"unclosed fence", "## a\n\n```\nls\n", "line 3: code block is never closed"},
+		{"text before commands", "- item\n", "line 1: expected a ## heading"},
+		{"section outside command", "### Examples\n", "line 1: section \"Examples\" is outside"},
+		{"unknown section", "## a\n### Usage\n", "line 2: unknown section \"Usage\""},
+		{"deep heading", "## a\n#### x\n", "line 2: unexpected level 4 heading"},
+		{"second syntax", "## a\n```\none\n```\n```\ntwo\n```\n", "line 5: command \"a\" already has a syntax block"},
+		{"bad metadata", "## a\n\nTags: x\nAuthor: me\n", "line 4: expected a line like Tags:"},
+		{"bad deprecation", "## a\n\nDeprecated: soon\n", `line 3: expected "since <version>" or "use <command>", got "soon"`},
+		{"output without example", "## a\n### Examples\n```output\nhi\n```\n", "line 3: an output block must follow the code of an example"},
+		{"bad exit code", "## a\n### Examples\n```\nfalse\n```\n```output exit=1\n```\n", `line 6: expected exitCode=N after output, got "exit=1"`},
+		{"option columns", "## a\n### Options\n| Flag | Description |\n| --- | --- |\n| -a |\n", "line 5: expected 2 columns"},
+		{"options not a table", "## a\n### Options\n- -a\n", "line 3: expected a | Flag | Description | table"},
+		{"front matter", "---\ntitle: [\n---\n## a\n", "front matter: yaml: line 2:"},
+		{"after front matter", "---\ntitle: x\n---\n\n## a\n### Nope\n", "line 6: unknown section"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeMarkdownSheets([]byte(test.text))
+			if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+				t.Errorf("Expected an error starting %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestMarkdownCommandLine(t *testing.T) {
+	data, err := os.ReadFile("testdata/markdown/sheet.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := markdownCommandLine(data, 0, "zip"); line != 48 {
+		t.Errorf("Expected zip on line 48, got %d", line)
+	}
+	if line := markdownCommandLine(data, 0, "missing"); line != 0 {
+		t.Errorf("Expected 0 for a missing command, got %d", line)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 )
@@ -8,6 +10,7 @@ import (
 // Model for our application
 type model struct {
 	cheatSheet            CheatSheet
+	vars                  map[string]string // variables the commands are expanded with: the sheet's under the overrides, nil when none apply
 	commands              []Command
 	tagMenu               []string
 	currentCommand        int
@@ -21,23 +24,74 @@ type model struct {
 	searchQuery           string // current search input text
 	searchActive          bool   // true when search filter is applied
 	err                   error  // Store any error that occurs
-	cheatsheets           []string // list of discovered cheatsheet files
+	cheatsheets           []sheetInfo // discovered cheatsheets matching the selector search
+	allCheatsheets        []sheetInfo // every discovered cheatsheet
+	sheetSearchMode       bool        // true when typing a selector search
+	sheetQuery            string      // selector search text
+	selectorRows          []selectorRow    // rows of the selector list: groups and sheets
+	selectorGrouping      selectorGrouping // how the selector groups the sheets
+	collapsedGroups       map[string]bool  // keys of collapsed selector groups
 	currentCheatsheet     int      // selected index in cheatsheet selector
 	showCheatsheetSelector bool    // true when showing cheatsheet selector
-	cheatsheetDir         string   // base directory for cheatsheets
+	cheatsheetDir         string   // base directory for cheatsheets, or a list of layered roots
+	lastClickTime         time.Time // time of the previous left click, for double-click detection
+	lastClickIndex        int       // item hit by the previous left click
+	jumpInput             string    // digits typed so far for "jump to number"
+	listOffset            int         // first content line of the command list shown on screen
+	searchIndex           searchIndex // lowercased command text for live search
+	tagFilter             tagFilter        // tags toggled on or excluded in the tag bar
+	complexityFilter      complexityFilter // complexity level the list is narrowed to
+	sortByComplexity      bool             // order the list from beginner to advanced
+	thisPlatformOnly      bool              // hide commands for other operating systems
+	versionMatchOnly      bool              // hide commands outside the installed version of their tool
+	toolVersions          map[string]string // installed tool versions detected so far, "" when unknown
+	availableOnly         bool              // hide commands whose programs aren't on $PATH
+	binaries              map[string]bool   // programs looked up on $PATH so far, true when found
+	expandOutput          bool              // show the example outputs of the detail view in full
+	editErr               error            // why the sheet failed to reload after editing
+	editCommand           string           // command being edited, selected again after reloading
+	editPath              string           // file that was edited, opened again when the edit broke the sheet
+	form                  *commandForm     // command form being filled in, nil when hidden
+	annotations           annotationStore      // the user's annotations of every sheet
+	annotationEdit        *annotationEditedMsg // annotation left unsaved by an edit that failed to parse
+	openCommand           string               // command named on the command line, shown once the sheet loads
 }
 
+// tagBarHeight is the height of the boxed tag bar: the tags and the filter
+// status line inside a border
+const tagBarHeight = 4
+
 // Define key mappings
 type keyMap struct {
-	Up           key.Binding
-	Down         key.Binding
-	Enter        key.Binding
-	Back         key.Binding
-	Quit         key.Binding
-	Search       key.Binding
-	Left         key.Binding
-	Right        key.Binding
-	OpenSelector key.Binding
+	Up              key.Binding
+	Down            key.Binding
+	Enter           key.Binding
+	Back            key.Binding
+	Quit            key.Binding
+	Search          key.Binding
+	Left            key.Binding
+	Right           key.Binding
+	OpenSelector    key.Binding
+	PageUp          key.Binding
+	PageDown        key.Binding
+	Home            key.Binding
+	End             key.Binding
+	ToggleTag       key.Binding
+	ExcludeTag      key.Binding
+	TagMode         key.Binding
+	Complexity      key.Binding
+	ComplexityExact key.Binding
+	SortComplexity  key.Binding
+	Grouping        key.Binding
+	Edit            key.Binding
+	AddCommand      key.Binding
+	EditForm        key.Binding
+	Platform        key.Binding
+	Versions        key.Binding
+	Replacement     key.Binding
+	Available       key.Binding
+	ExpandOutput    key.Binding
+	Annotate        key.Binding
 }
 
 var keys = keyMap{
@@ -77,4 +131,84 @@ var keys = keyMap{
 		key.WithKeys("o"),
 		key.WithHelp("o", "open cheatsheet"),
 	),
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page up"),
+	),
+	PageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdn", "page down"),
+	),
+	Home: key.NewBinding(
+		key.WithKeys("home", "g"),
+		key.WithHelp("g/home", "first command"),
+	),
+	End: key.NewBinding(
+		key.WithKeys("end", "G"),
+		key.WithHelp("G/end", "last command"),
+	),
+	ToggleTag: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "toggle tag"),
+	),
+	ExcludeTag: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "exclude tag"),
+	),
+	TagMode: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "match any/all tags"),
+	),
+	Complexity: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "cycle complexity level"),
+	),
+	ComplexityExact: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "exact/cumulative complexity"),
+	),
+	SortComplexity: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "sort by complexity"),
+	),
+	Grouping: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "switch selector view"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit in $EDITOR"),
+	),
+	AddCommand: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "add command"),
+	),
+	EditForm: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "edit in form"),
+	),
+	Platform: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "hide other platforms"),
+	),
+	Versions: key.NewBinding(
+		key.WithKeys("V"),
+		key.WithHelp("V", "match installed versions"),
+	),
+	Replacement: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "go to replacement"),
+	),
+	Available: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "installed only"),
+	),
+	ExpandOutput: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "expand output"),
+	),
+	Annotate: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "annotate"),
+	),
 }
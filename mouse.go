@@ -0,0 +1,160 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickInterval is the longest gap between two clicks on the same item
+// that still counts as a double-click
+const doubleClickInterval = 400 * time.Millisecond
+
+// handleMouse routes mouse events to the selector, command list or detail view
+func (m model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+
+	switch {
+	case m.showCheatsheetSelector:
+		return m.handleSelectorMouse(msg)
+	case m.showDetail:
+		// The viewport scrolls the detail text on wheel events by itself
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	case m.searchMode:
+		return m, nil
+	default:
+		return m.handleListMouse(msg)
+	}
+}
+
+// handleSelectorMouse selects a row on click, loads the sheet or folds the
+// group on double-click and moves the selection with the wheel
+func (m model) handleSelectorMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		if m.currentCheatsheet > 0 {
+			m.selectCheatsheet(m.currentCheatsheet - 1)
+		}
+	case tea.MouseButtonWheelDown:
+		if m.currentCheatsheet < len(m.selectorRows)-1 {
+			m.selectCheatsheet(m.currentCheatsheet + 1)
+		}
+	case tea.MouseButtonLeft:
+		if msg.X >= m.selectorListWidth() {
+			// Clicks on the preview pane
+			return m, nil
+		}
+		idx, ok := CheatsheetAtLine(len(m.selectorRows), m.viewportLine(msg.Y))
+		if !ok {
+			return m, nil
+		}
+		m.selectCheatsheet(idx)
+		if m.registerClick(idx) {
+			return m, m.activateSelectorRow()
+		}
+	}
+	return m, nil
+}
+
+// handleListMouse selects a command on click, opens it on double-click, moves
+// the selection with the wheel and switches tags when the tag bar is clicked
+func (m model) handleListMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		if m.currentCommand > 0 {
+			m.selectCommand(m.currentCommand - 1)
+		}
+	case tea.MouseButtonWheelDown:
+		if m.currentCommand < len(m.commands)-1 {
+			m.selectCommand(m.currentCommand + 1)
+		}
+	case tea.MouseButtonLeft:
+		if row := m.tagBarRow(); row >= 0 && msg.Y == row {
+			m.clickTag(msg.X)
+			return m, nil
+		}
+		idx, ok := CommandAtLine(m.cheatSheet.Description, len(m.commands), m.viewportLine(msg.Y))
+		if !ok {
+			return m, nil
+		}
+		m.selectCommand(idx)
+		if m.registerClick(idx) {
+			m.openDetail()
+		}
+	}
+	return m, nil
+}
+
+// clickTag selects the tag under column x, or steps through the tags when one
+// of the scroll indicators was clicked
+func (m *model) clickTag(x int) {
+	idx, ok := TagAtColumn(m.tagMenu, m.currentTag, m.width, m.tagFilter, x)
+	if !ok {
+		return
+	}
+	switch idx {
+	case tagScrollLeft:
+		if m.currentTag > 0 {
+			m.selectTag(m.currentTag - 1)
+		}
+	case tagScrollRight:
+		if m.currentTag < len(m.tagMenu)-1 {
+			m.selectTag(m.currentTag + 1)
+		}
+	default:
+		if idx != m.currentTag {
+			m.selectTag(idx)
+		}
+	}
+}
+
+// registerClick records a left click on item idx and reports whether it
+// completes a double-click on that same item
+func (m *model) registerClick(idx int) bool {
+	now := time.Now()
+	double := idx == m.lastClickIndex && now.Sub(m.lastClickTime) <= doubleClickInterval
+	m.lastClickIndex = idx
+	m.lastClickTime = now
+	if double {
+		// A third click starts a new sequence
+		m.lastClickTime = time.Time{}
+	}
+	return double
+}
+
+// viewportTop returns the screen row of the first line of the main viewport
+func (m model) viewportTop() int {
+	top := 0
+	for _, part := range m.headerParts() {
+		top += lipgloss.Height(part) + 1 // parts are separated by a blank line
+	}
+	return top
+}
+
+// viewportLine maps screen row y to a line of the main viewport's content,
+// or -1 when y lies outside the viewport
+func (m model) viewportLine(y int) int {
+	top := m.viewportTop()
+	if y < top || y >= top+m.viewport.Height {
+		return -1
+	}
+	if m.listVisible() {
+		return y - top + m.listOffset
+	}
+	return y - top + m.viewport.YOffset
+}
+
+// tagBarRow returns the screen row holding the tags of the tag bar, or -1 when
+// the tag bar isn't shown
+func (m model) tagBarRow() int {
+	if m.showCheatsheetSelector || m.showDetail || m.searchMode || len(m.tagMenu) == 0 {
+		return -1
+	}
+	header := m.headerParts()[0]
+	return lipgloss.Height(header) + 1 + boxedViewportStyle.GetBorderTopSize()
+}
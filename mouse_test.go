@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newListModel returns a model showing a sheet with n commands in a terminal
+// of the given size
+func newListModel(n, width, height int) model {
+	sheet := CheatSheet{Title: "Test", Description: "Test sheet"}
+	for i := 0; i < n; i++ {
+		tag := "even"
+		if i%2 == 1 {
+			tag = "odd"
+		}
+		sheet.Commands = append(sheet.Commands, Command{
+			Name:      fmt.Sprintf("cmd%d", i),
+			ShortDesc: fmt.Sprintf("command %d", i),
+			Tags:      []string{tag},
+		})
+	}
+
+	var tm tea.Model = initialModel("", "cheatsheets")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: width, Height: height})
+	tm, _ = tm.Update(cheatSheetLoadedMsg(sheet))
+	return tm.(model)
+}
+
+func click(m model, x, y int) model {
+	tm, _ := m.Update(tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	return tm.(model)
+}
+
+func TestMouseClickSelectsCommand(t *testing.T) {
+	m := newListModel(5, 80, 40)
+
+	y := m.viewportTop() + commandListLine(m.cheatSheet.Description, 3)
+	m = click(m, 5, y)
+	if m.currentCommand != 3 {
+		t.Fatalf("Expected command 3 to be selected, got %d", m.currentCommand)
+	}
+	if m.showDetail {
+		t.Errorf("Expected a single click not to open the detail view")
+	}
+
+	// A blank line between two commands selects nothing
+	m = click(m, 5, y+1)
+	if m.currentCommand != 3 {
+		t.Errorf("Expected selection to stay at 3, got %d", m.currentCommand)
+	}
+
+	// Two clicks on the same command open it
+	m = click(m, 5, y)
+	m = click(m, 5, y)
+	if !m.showDetail {
+		t.Errorf("Expected a double-click to open the detail view")
+	}
+}
+
+func TestMouseWheelMovesSelection(t *testing.T) {
+	m := newListModel(5, 80, 40)
+
+	tm, _ := m.Update(tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonWheelDown})
+	m = tm.(model)
+	if m.currentCommand != 1 {
+		t.Errorf("Expected wheel down to select command 1, got %d", m.currentCommand)
+	}
+	tm, _ = m.Update(tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonWheelUp})
+	m = tm.(model)
+	if m.currentCommand != 0 {
+		t.Errorf("Expected wheel up to select command 0, got %d", m.currentCommand)
+	}
+}
+
+func TestMouseClickSelectsTag(t *testing.T) {
+	// The tag menu is all, even, odd
+	for _, width := range []int{80, 120} {
+		m := newListModel(4, width, 40)
+
+		// Find the column of the "odd" tag from the layout
+		target := -1
+		for x := 0; x < width; x++ {
+			if idx, ok := TagAtColumn(m.tagMenu, m.currentTag, m.width, m.tagFilter, x); ok && idx == 2 {
+				target = x
+				break
+			}
+		}
+		if target < 0 {
+			t.Fatalf("width %d: tag 'odd' not found in the tag bar", width)
+		}
+
+		m = click(m, target, m.tagBarRow())
+		if m.tagMenu[m.currentTag] != "odd" {
+			t.Errorf("width %d: expected tag 'odd', got '%s'", width, m.tagMenu[m.currentTag])
+		}
+		if len(m.commands) != 2 {
+			t.Errorf("width %d: expected 2 commands after filtering, got %d", width, len(m.commands))
+		}
+	}
+}
+
+func TestTagAtColumnScrollIndicators(t *testing.T) {
+	tags := []string{"all", "alpha", "bravo", "charlie", "delta", "echo", "foxtrot"}
+
+	// Narrow enough that only a couple of tags fit around the selection
+	width := 30
+	segments := visibleTagSegments(tags, 3, width, tagFilter{})
+	if segments[0].index != tagScrollLeft || segments[len(segments)-1].index != tagScrollRight {
+		t.Fatalf("Expected both scroll indicators, got %+v", segments)
+	}
+
+	idx, ok := TagAtColumn(tags, 3, width, tagFilter{}, tagMenuLeftInset())
+	if !ok || idx != tagScrollLeft {
+		t.Errorf("Expected the first column to hit the left indicator, got %d (%v)", idx, ok)
+	}
+
+	if _, ok := TagAtColumn(tags, 3, width, tagFilter{}, 0); ok {
+		t.Errorf("Expected the box border not to hit any tag")
+	}
+}
+
+func TestViewportTopMatchesView(t *testing.T) {
+	m := newListModel(3, 80, 30)
+
+	// The first command must be drawn on the row the mouse mapping expects
+	lines := strings.Split(m.View(), "\n")
+	row := m.viewportTop() + commandListLine(m.cheatSheet.Description, 0)
+	if row >= len(lines) || !strings.Contains(lines[row], "cmd0") {
+		t.Errorf("Expected 'cmd0' on row %d, got %q", row, lines[row])
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func press(m model, k tea.KeyMsg) model {
+	tm, _ := m.Update(k)
+	return tm.(model)
+}
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// assertSelectedVisible fails when the selected command's line lies outside
+// the viewport
+func assertSelectedVisible(t *testing.T, m model, context string) {
+	t.Helper()
+	line := commandListLine(m.cheatSheet.Description, m.currentCommand)
+	if line < m.listOffset || line >= m.listOffset+m.viewport.Height {
+		t.Errorf("%s: command %d on line %d is outside the viewport (offset %d, height %d)",
+			context, m.currentCommand, line, m.listOffset, m.viewport.Height)
+	}
+}
+
+func TestSelectedCommandStaysVisible(t *testing.T) {
+	for _, height := range []int{12, 15, 24, 40, 80} {
+		m := newListModel(60, 80, height)
+
+		for i := 0; i < 59; i++ {
+			m = press(m, tea.KeyMsg{Type: tea.KeyDown})
+			assertSelectedVisible(t, m, "down")
+		}
+		if m.currentCommand != 59 {
+			t.Errorf("height %d: expected to reach the last command, got %d", height, m.currentCommand)
+		}
+
+		for i := 0; i < 59; i++ {
+			m = press(m, tea.KeyMsg{Type: tea.KeyUp})
+			assertSelectedVisible(t, m, "up")
+		}
+		if height >= 15 && m.listOffset != 0 {
+			t.Errorf("height %d: expected the list scrolled to the top, got offset %d", height, m.listOffset)
+		}
+	}
+}
+
+func TestPageAndHomeEndKeys(t *testing.T) {
+	for _, height := range []int{12, 24, 50} {
+		m := newListModel(60, 80, height)
+		page := m.commandsPerPage()
+
+		m = press(m, tea.KeyMsg{Type: tea.KeyPgDown})
+		if m.currentCommand != page {
+			t.Errorf("height %d: expected PgDn to select %d, got %d", height, page, m.currentCommand)
+		}
+		assertSelectedVisible(t, m, "pgdown")
+
+		m = press(m, runes("G"))
+		if m.currentCommand != 59 {
+			t.Errorf("height %d: expected G to select the last command, got %d", height, m.currentCommand)
+		}
+		assertSelectedVisible(t, m, "end")
+
+		m = press(m, tea.KeyMsg{Type: tea.KeyPgUp})
+		if m.currentCommand != 59-page {
+			t.Errorf("height %d: expected PgUp to select %d, got %d", height, 59-page, m.currentCommand)
+		}
+		assertSelectedVisible(t, m, "pgup")
+
+		m = press(m, runes("g"))
+		if m.currentCommand != 0 {
+			t.Errorf("height %d: expected g to return to the top, got %d", height, m.currentCommand)
+		}
+		assertSelectedVisible(t, m, "home")
+	}
+}
+
+func TestJumpToNumber(t *testing.T) {
+	for _, height := range []int{12, 30} {
+		m := newListModel(60, 80, height)
+
+		m = press(m, runes("4"))
+		m = press(m, runes("2"))
+		if m.jumpInput != "42" {
+			t.Fatalf("Expected jump input '42', got '%s'", m.jumpInput)
+		}
+		m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+		if m.currentCommand != 41 {
+			t.Errorf("height %d: expected command 42 (index 41), got %d", height, m.currentCommand)
+		}
+		if m.showDetail {
+			t.Errorf("height %d: expected Enter to jump instead of opening the detail view", height)
+		}
+		assertSelectedVisible(t, m, "jump")
+
+		// Out of range numbers leave the selection alone
+		m = press(m, runes("99"))
+		m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+		if m.currentCommand != 41 || m.jumpInput != "" {
+			t.Errorf("height %d: expected an invalid jump to be ignored, got %d", height, m.currentCommand)
+		}
+	}
+}
+
+func TestScrollOffsetFor(t *testing.T) {
+	tests := []struct {
+		line, offset, height, want int
+	}{
+		{5, 0, 10, 0},  // already visible
+		{12, 0, 10, 3}, // below the view
+		{2, 8, 10, 2},  // above the view
+		{9, 0, 10, 0},  // last visible line
+		{10, 0, 10, 1}, // first line past the view
+		{4, 3, 0, 3},   // no room to show anything
+	}
+	for _, tt := range tests {
+		if got := scrollOffsetFor(tt.line, tt.offset, tt.height); got != tt.want {
+			t.Errorf("scrollOffsetFor(%d, %d, %d) = %d, want %d", tt.line, tt.offset, tt.height, got, tt.want)
+		}
+	}
+}
@@ -1,56 +1,117 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 type Option struct {
-	Flag        string `yaml:"flag"`
-	Description string `yaml:"description"`
+	Flag        string `yaml:"flag" json:"flag,omitempty" toml:"flag,omitempty"`
+	Description string `yaml:"description" json:"description,omitempty" toml:"description,omitempty"`
 }
 
 type Example struct {
-	Code        string `yaml:"code"`
-	Description string `yaml:"description"`
+	Code        string        `yaml:"code" json:"code,omitempty" toml:"code,omitempty"`
+	Description string        `yaml:"description" json:"description,omitempty" toml:"description,omitempty"`
+	Output      string        `yaml:"output,omitempty" json:"output,omitempty" toml:"output,omitempty"`       // what the code prints, as literal text
+	ExitCode    int           `yaml:"exitCode,omitempty" json:"exitCode,omitempty" toml:"exitCode,omitempty"` // status the code exits with, when it isn't 0
+	Verify      *Verification `yaml:"verify,omitempty" json:"verify,omitempty" toml:"verify,omitempty"`       // how "cheatcheat verify" checks the example; not checked when nil
 }
 
 type Command struct {
-	Name       string    `yaml:"name"`
-	ShortDesc  string    `yaml:"shortDesc"`
-	Syntax     string    `yaml:"syntax"`
-	Tags       []string  `yaml:"tags"`
-	Complexity string    `yaml:"complexity"`
-	Examples   []Example `yaml:"examples"`
-	Notes      []string  `yaml:"notes"`
-	Options    []Option  `yaml:"options"`
-	Related    []string  `yaml:"related"`
+	Name       string       `yaml:"name" json:"name" toml:"name"`
+	ShortDesc  string       `yaml:"shortDesc,omitempty" json:"shortDesc,omitempty" toml:"shortDesc,omitempty"`
+	Syntax     string       `yaml:"syntax,omitempty" json:"syntax,omitempty" toml:"syntax,omitempty"`
+	Tags       []string     `yaml:"tags,omitempty" json:"tags,omitempty" toml:"tags,omitempty"`
+	Complexity string       `yaml:"complexity,omitempty" json:"complexity,omitempty" toml:"complexity,omitempty"`
+	Examples   []Example    `yaml:"examples,omitempty" json:"examples,omitempty" toml:"examples,omitempty"`
+	Notes      []string     `yaml:"notes,omitempty" json:"notes,omitempty" toml:"notes,omitempty"`
+	Options    []Option     `yaml:"options,omitempty" json:"options,omitempty" toml:"options,omitempty"`
+	Related    []string     `yaml:"related,omitempty" json:"related,omitempty" toml:"related,omitempty"`
+	Aliases    []string     `yaml:"aliases,omitempty" json:"aliases,omitempty" toml:"aliases,omitempty"`          // other names the command is known by, also accepted by related
+	Keywords   []string     `yaml:"keywords,omitempty" json:"keywords,omitempty" toml:"keywords,omitempty"`       // extra words search finds the command by, like "undo commit"
+	Platforms  []string     `yaml:"platforms,omitempty" json:"platforms,omitempty" toml:"platforms,omitempty"`    // operating systems the command applies to, named like GOOS; all when empty
+	MinVersion string       `yaml:"minVersion,omitempty" json:"minVersion,omitempty" toml:"minVersion,omitempty"` // first version of the tool that has the command
+	MaxVersion string       `yaml:"maxVersion,omitempty" json:"maxVersion,omitempty" toml:"maxVersion,omitempty"` // last version of the tool that has the command
+	Deprecated *Deprecation `yaml:"deprecated,omitempty" json:"deprecated,omitempty" toml:"deprecated,omitempty"`
+	Source     string       `yaml:"-" json:"-" toml:"-"` // sheet the command was inherited or included from, empty for its own sheet
+	Overrides  string       `yaml:"-" json:"-" toml:"-"` // sheet whose command of the same name this one replaces
+	Missing    []string     `yaml:"-" json:"-" toml:"-"` // programs the command runs that aren't on $PATH
+	Annotation *Annotation  `yaml:"-" json:"-" toml:"-"` // the user's own notes on the command, kept outside the sheet
 }
 
 type CheatSheet struct {
-	Title       string    `yaml:"title"`
-	Description string    `yaml:"description"`
-	Category    string    `yaml:"category"`
-	Commands    []Command `yaml:"commands"`
+	Title       string            `yaml:"title" json:"title" toml:"title"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty" toml:"description,omitempty"`
+	Category    string            `yaml:"category,omitempty" json:"category,omitempty" toml:"category,omitempty"`
+	Extends     string            `yaml:"extends,omitempty" json:"extends,omitempty" toml:"extends,omitempty"` // sheet whose commands this one builds on
+	Include     []string          `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"` // sheets, or globs of them, whose commands are added
+	Drop        []string          `yaml:"drop,omitempty" json:"drop,omitempty" toml:"drop,omitempty"`          // inherited commands to leave out
+	Vars        map[string]string `yaml:"vars,omitempty" json:"vars,omitempty" toml:"vars,omitempty"`          // default values of the variables in syntax, example code and notes
+	Commands    []Command         `yaml:"commands" json:"commands" toml:"commands"`
+	Path        string            `yaml:"-" json:"-" toml:"-"` // file the sheet was loaded from
+	Document    int               `yaml:"-" json:"-" toml:"-"` // index of the sheet within a multi-document file
 }
 
-// Load a cheatsheet from file
+// Load a cheatsheet from file. Files holding several sheets yield the first.
 func LoadCheatSheet(filename string) (CheatSheet, error) {
-	var sheet CheatSheet
+	return loadCheatSheetAt(filename, 0)
+}
+
+// loadCheatSheetAt loads sheet number doc of the file
+func loadCheatSheetAt(filename string, doc int) (CheatSheet, error) {
+	sheets, err := LoadCheatSheets(filename)
+	if err != nil {
+		return CheatSheet{}, err
+	}
+	if doc >= len(sheets) {
+		return CheatSheet{}, fmt.Errorf("%s: no document %d, the file holds %d", filename, doc+1, len(sheets))
+	}
+	return sheets[doc], nil
+}
+
+// LoadCheatSheets loads every sheet in a file, with the commands they extend
+// and include resolved
+func LoadCheatSheets(filename string) ([]CheatSheet, error) {
+	sheets, err := decodeSheetFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sheets {
+		if err := resolveSheet(&sheets[i], filename, []string{filename}); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, documentError(i, err))
+		}
+		if err := validateSheet(&sheets[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, documentError(i, err))
+		}
+	}
+	return sheets, nil
+}
+
+// decodeSheetFile reads the sheets in a file as they are written, decoding
+// them by its extension and falling back to YAML for unknown ones
+func decodeSheetFile(filename string) ([]CheatSheet, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return sheet, err
+		return nil, err
+	}
+	format, _ := formatForPath(filename)
+	sheets, err := format.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
 	}
-	err = yaml.Unmarshal(data, &sheet)
-	return sheet, err
+	for i := range sheets {
+		sheets[i].Path = filename
+		sheets[i].Document = i
+	}
+	return sheets, nil
 }
 
-// DiscoverCheatsheets recursively scans a directory for .yaml files
-// and returns their relative paths sorted alphabetically
+// DiscoverCheatsheets recursively scans a directory for files in one of the
+// registered sheet formats and returns their relative paths sorted
+// alphabetically
 func DiscoverCheatsheets(dir string) ([]string, error) {
 	var cheatsheets []string
 
@@ -64,8 +125,17 @@ func DiscoverCheatsheets(dir string) ([]string, error) {
 			return nil
 		}
 
-		// Only include .yaml files
-		if strings.HasSuffix(strings.ToLower(path), ".yaml") {
+		// Only include files in a format we can read, and that are sheets
+		if format, ok := formatForPath(path); ok {
+			if format.isSheet != nil {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if !format.isSheet(data) {
+					return nil
+				}
+			}
 			// Get relative path from base directory
 			relPath, err := filepath.Rel(dir, path)
 			if err != nil {
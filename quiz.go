@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quizInput is where "cheatcheat quiz" reads answers from
+var quizInput io.Reader = os.Stdin
+
+// quizNow is the time reviews are scheduled from
+var quizNow = time.Now
+
+// reviewState is the spaced repetition schedule of one card, as kept by SM-2
+type reviewState struct {
+	Repetitions int       `json:"repetitions"` // correct answers in a row
+	Interval    int       `json:"interval"`    // days until the next review
+	Ease        float64   `json:"ease"`        // how fast the interval grows
+	Due         time.Time `json:"due"`         // when the card is next asked
+}
+
+// initialEase is the ease a card starts with in SM-2
+const initialEase = 2.5
+
+// review schedules the card after an answer graded from 0 (blank) to 5
+// (perfect). Grades below 3 start the card over; otherwise its interval
+// grows by its ease, which itself follows how hard the answer was.
+func (r reviewState) review(grade int, now time.Time) reviewState {
+	if r.Ease == 0 {
+		r.Ease = initialEase
+	}
+	if grade < 3 {
+		r.Repetitions = 0
+		r.Interval = 1
+	} else {
+		r.Repetitions++
+		switch r.Repetitions {
+		case 1:
+			r.Interval = 1
+		case 2:
+			r.Interval = 6
+		default:
+			r.Interval = int(math.Round(float64(r.Interval) * r.Ease))
+		}
+	}
+	miss := float64(5 - grade)
+	r.Ease = math.Max(1.3, r.Ease+0.1-miss*(0.08+miss*0.02))
+	r.Due = now.AddDate(0, 0, r.Interval)
+	return r
+}
+
+// quizState holds the schedule of every card, by sheet key and card ID
+type quizState map[string]map[string]reviewState
+
+// loadQuizState reads the saved quiz schedules. A missing state file is not
+// an error and yields no schedules.
+func loadQuizState() (quizState, error) {
+	state := make(quizState)
+	dir, err := stateDir()
+	if err != nil {
+		return state, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "quiz.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// saveQuizState writes the quiz schedules for the next session
+func saveQuizState(state quizState) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "quiz.json"), data, 0644)
+}
+
+// quizCard is one question of the quiz: a description to answer with a
+// command, or with --reverse a command to recall the description of
+type quizCard struct {
+	ID      string   // stable name of the card in the quiz state
+	Prompt  string   // what the user is shown
+	Answer  string   // what is shown as the right answer
+	Accept  []string // answers graded as perfect
+	Syntax  string   // syntax answers may fit, with placeholders filled in
+	Reverse bool     // graded by the user rather than by comparison
+}
+
+// quizCards makes the cards of commands: one asking for each command from
+// its short description, and one asking for each described example
+func quizCards(commands []Command, reverse bool) []quizCard {
+	var cards []quizCard
+	for _, cmd := range commands {
+		codes := []string{cmd.Syntax}
+		for _, ex := range cmd.Examples {
+			codes = append(codes, ex.Code)
+		}
+		if cmd.ShortDesc != "" && cmd.Syntax != "" {
+			cards = append(cards, quizCard{ID: cmd.Name, Prompt: cmd.ShortDesc, Answer: cmd.Syntax, Accept: codes, Syntax: cmd.Syntax})
+		}
+		for i, ex := range cmd.Examples {
+			if ex.Description == "" || ex.Code == "" {
+				continue
+			}
+			id := fmt.Sprintf("%s#%d", cmd.Name, i+1)
+			cards = append(cards, quizCard{ID: id, Prompt: ex.Description, Answer: ex.Code, Accept: []string{ex.Code}, Syntax: cmd.Syntax})
+		}
+	}
+	if reverse {
+		for i, card := range cards {
+			cards[i] = quizCard{ID: "reverse:" + card.ID, Prompt: card.Answer, Answer: card.Prompt, Reverse: true}
+		}
+	}
+	return cards
+}
+
+// quizQuotes are the quote characters answers may use interchangeably
+var quizQuotes = strings.NewReplacer("'", `"`, "‘", `"`, "’", `"`, "“", `"`, "”", `"`)
+
+// normalizeCommand reduces a command line to what matters when grading: no
+// prompt, surrounding space or trailing semicolon, single spaces and one
+// kind of quote
+func normalizeCommand(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "$ ")
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.TrimRight(s, "; ")
+	return quizQuotes.Replace(s)
+}
+
+// syntaxPattern turns a syntax line into a pattern for the commands it
+// describes: <placeholders>, {{ variables }} and bare names in brackets like
+// [dir] stand for any text, [optional parts] may be left out and "..." allows
+// more arguments
+func syntaxPattern(syntax string) *regexp.Regexp {
+	s := normalizeCommand(syntax)
+	pattern, err := regexp.Compile("^" + wordsPattern(s) + "$")
+	if err != nil {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(s) + "$")
+	}
+	return pattern
+}
+
+// wordsPattern matches the space separated words of a syntax line, where
+// an optional word takes its leading space with it
+func wordsPattern(s string) string {
+	var b strings.Builder
+	for i, word := range syntaxWords(s) {
+		sep := " "
+		if i == 0 {
+			sep = ""
+		}
+		switch {
+		case word == "...":
+			b.WriteString("(?:" + sep + ".*)?")
+		case bareName.MatchString(word):
+			b.WriteString("(?:" + sep + ".+)?")
+		case word[0] == '[' && closingBracket(word) == len(word)-1:
+			b.WriteString("(?:" + sep + wordsPattern(word[1:len(word)-1]) + ")?")
+		default:
+			b.WriteString(sep + wordPattern(word))
+		}
+	}
+	return b.String()
+}
+
+// bareName matches an optional word naming what goes there, like [dir] or
+// [options], rather than spelling it out like [-s]
+var bareName = regexp.MustCompile(`^\[[A-Za-z][A-Za-z0-9_]*\]$`)
+
+// wordPattern matches a single word of a syntax line
+func wordPattern(word string) string {
+	var b strings.Builder
+	for word != "" {
+		end := 1
+		switch {
+		case word[0] == '<' && strings.IndexByte(word, '>') > 0:
+			end = strings.IndexByte(word, '>') + 1
+			b.WriteString(".+")
+		case strings.HasPrefix(word, "{{") && strings.Index(word, "}}") > 0:
+			end = strings.Index(word, "}}") + 2
+			b.WriteString(".+")
+		case word[0] == '[' && closingBracket(word) > 0:
+			end = closingBracket(word) + 1
+			b.WriteString("(?:" + wordsPattern(word[1:end-1]) + ")?")
+		case strings.HasPrefix(word, "..."):
+			end = 3
+			b.WriteString(".*")
+		default:
+			b.WriteString(regexp.QuoteMeta(word[:1]))
+		}
+		word = word[end:]
+	}
+	return b.String()
+}
+
+// syntaxWords splits a syntax line at the spaces outside brackets, so
+// "[-m <message>]" and "{{ .branch }}" stay whole
+func syntaxWords(s string) []string {
+	var words []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '[', '<', '{':
+			depth++
+		case ']', '>', '}':
+			depth = max(0, depth-1)
+		case ' ':
+			if depth == 0 {
+				if i > start {
+					words = append(words, s[start:i])
+				}
+				start = i + 1
+			}
+		}
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// closingBracket returns the index of the "]" closing the "[" s starts with,
+// or -1
+func closingBracket(s string) int {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// gradeAnswer grades an answer to card from 0 to 5, as SM-2 expects: 5 for
+// one of the accepted commands, 4 for a command the syntax describes, 3 for
+// one fitting the syntax of an example's command, 1 for a wrong answer and 0
+// for none
+func gradeAnswer(card quizCard, answer string) int {
+	answer = normalizeCommand(answer)
+	if answer == "" {
+		return 0
+	}
+	for _, accept := range card.Accept {
+		if normalizeCommand(accept) == answer {
+			return 5
+		}
+	}
+	if card.Syntax != "" && syntaxPattern(card.Syntax).MatchString(answer) {
+		if card.Syntax == card.Answer {
+			return 4
+		}
+		return 3
+	}
+	return 1
+}
+
+// quizDeck picks the cards to ask: those due, longest overdue first, then
+// those never asked, at most limit of them
+func quizDeck(cards []quizCard, schedules map[string]reviewState, now time.Time, limit int) []quizCard {
+	var due, fresh []quizCard
+	for _, card := range cards {
+		state, ok := schedules[card.ID]
+		switch {
+		case !ok:
+			fresh = append(fresh, card)
+		case !state.Due.After(now):
+			due = append(due, card)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return schedules[due[i].ID].Due.Before(schedules[due[j].ID].Due)
+	})
+	deck := append(due, fresh...)
+	if limit > 0 && len(deck) > limit {
+		deck = deck[:limit]
+	}
+	return deck
+}
+
+// nextReview returns when the first of cards is due again, the zero time
+// when none has been asked
+func nextReview(cards []quizCard, schedules map[string]reviewState) time.Time {
+	var next time.Time
+	for _, card := range cards {
+		if state, ok := schedules[card.ID]; ok && (next.IsZero() || state.Due.Before(next)) {
+			next = state.Due
+		}
+	}
+	return next
+}
+
+// findQuizSheet returns the path of the sheet to quiz on: the path given, or
+// failing that one found under a cheatsheet root
+func findQuizSheet(name string) string {
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	for _, root := range sheetRoots {
+		if path := filepath.Join(root, name); fileExists(path) {
+			return path
+		}
+	}
+	return name
+}
+
+// fileExists reports whether path names an existing file
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// runQuiz implements "cheatcheat quiz": flashcards from a sheet, asking for
+// commands by their description, or with --reverse the other way around.
+// Answers are graded and the cards scheduled with SM-2 in the local state, so
+// each session asks what is due.
+func runQuiz(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("quiz", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	reverse := flags.Bool("reverse", false, "show commands and recall what they do")
+	limit := flags.Int("limit", 20, "cards to ask at most, 0 for all that are due")
+	complexity := flags.String("complexity", "", "ask only commands up to this complexity")
+	var tags []string
+	flags.Func("tag", "ask only commands with this tag (repeatable)", func(tag string) error {
+		tags = append(tags, tag)
+		return nil
+	})
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat quiz [--reverse] [--tag t]... [--complexity level] [--limit n] sheet")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	filter := commandFilter{tags: tagFilter{include: tags}}
+	if *complexity != "" {
+		level, ok := parseComplexity(*complexity)
+		if !ok {
+			fmt.Fprintf(stderr, "unknown complexity %q, want beginner, intermediate or advanced\n", *complexity)
+			return 2
+		}
+		filter.complexity = complexityFilter{level: level}
+	}
+
+	sheet, err := LoadCheatSheet(findQuizSheet(flags.Arg(0)))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	key := annotationKey(sheet)
+	if annotations, err := loadAnnotations(); err == nil {
+		sheet.Commands = annotateCommands(sheet.Commands, annotations[key])
+	}
+	var commands []Command
+	for _, cmd := range sheet.Commands {
+		if filter.Matches(cmd) {
+			commands = append(commands, expandCommand(cmd, sheetVars(sheet)))
+		}
+	}
+	state, err := loadQuizState()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if state[key] == nil {
+		state[key] = make(map[string]reviewState)
+	}
+	schedules := state[key]
+
+	cards := quizCards(commands, *reverse)
+	deck := quizDeck(cards, schedules, quizNow(), *limit)
+	if len(deck) == 0 {
+		fmt.Fprintf(stdout, "Nothing due in %s.", sheet.Title)
+		if next := nextReview(cards, schedules); !next.IsZero() {
+			fmt.Fprintf(stdout, " Next review: %s", next.Format("2006-01-02"))
+		}
+		fmt.Fprintln(stdout)
+		return 0
+	}
+
+	fmt.Fprintf(stdout, "%s: %s\n", sheet.Title, plural(len(deck), "card"))
+	in := bufio.NewScanner(quizInput)
+	asked, correct := 0, 0
+	for i, card := range deck {
+		fmt.Fprintf(stdout, "\n[%d/%d] %s\n", i+1, len(deck), card.Prompt)
+		grade, ok := askCard(card, in, stdout)
+		if !ok {
+			break
+		}
+		asked++
+		if grade >= 3 {
+			correct++
+		}
+		schedules[card.ID] = schedules[card.ID].review(grade, quizNow())
+	}
+
+	if err := saveQuizState(state); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "\n%d of %d correct.", correct, asked)
+	if next := nextReview(cards, schedules); !next.IsZero() {
+		fmt.Fprintf(stdout, " Next review: %s", next.Format("2006-01-02"))
+	}
+	fmt.Fprintln(stdout)
+	return 0
+}
+
+// askCard asks card and returns its grade. ok is false when the input ends
+// before the card is answered.
+func askCard(card quizCard, in *bufio.Scanner, stdout io.Writer) (grade int, ok bool) {
+	if card.Reverse {
+		fmt.Fprint(stdout, "(Enter shows the answer) ")
+		if !in.Scan() {
+			return 0, false
+		}
+		fmt.Fprintf(stdout, "→ %s\n", card.Answer)
+		for {
+			fmt.Fprint(stdout, "How well did you recall it, 0 (not at all) to 5 (perfectly)? ")
+			if !in.Scan() {
+				return 0, false
+			}
+			if grade, err := strconv.Atoi(strings.TrimSpace(in.Text())); err == nil && grade >= 0 && grade <= 5 {
+				return grade, true
+			}
+		}
+	}
+
+	fmt.Fprint(stdout, "> ")
+	if !in.Scan() {
+		return 0, false
+	}
+	grade = gradeAnswer(card, in.Text())
+	if grade >= 3 {
+		fmt.Fprintf(stdout, "✓ %s\n", card.Answer)
+	} else {
+		fmt.Fprintf(stdout, "✗ %s\n", card.Answer)
+	}
+	return grade, true
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReviewSchedule(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	var r reviewState
+	var intervals []int
+	for _, grade := range []int{5, 4, 4, 5} {
+		r = r.review(grade, now)
+		intervals = append(intervals, r.Interval)
+	}
+	if want := []int{1, 6, 16, 42}; !reflect.DeepEqual(intervals, want) {
+		t.Errorf("got intervals %v, want %v", intervals, want)
+	}
+	if !r.Due.Equal(now.AddDate(0, 0, 42)) {
+		t.Errorf("expected the card due in 42 days, got %s", r.Due)
+	}
+
+	r = r.review(1, now)
+	if r.Repetitions != 0 || r.Interval != 1 {
+		t.Errorf("expected a wrong answer to start the card over, got %+v", r)
+	}
+	for i := 0; i < 10; i++ {
+		r = r.review(0, now)
+	}
+	if math.Abs(r.Ease-1.3) > 1e-9 {
+		t.Errorf("expected the ease floored at 1.3, got %v", r.Ease)
+	}
+}
+
+func TestGradeAnswer(t *testing.T) {
+	card := quizCard{
+		Answer: "git commit [-a] -m <message>",
+		Accept: []string{"git commit [-a] -m <message>", "git commit -m 'Fix typo'"},
+		Syntax: "git commit [-a] -m <message>",
+	}
+	tests := []struct {
+		answer string
+		want   int
+	}{
+		{`$ git  commit -m "Fix typo";`, 5},
+		{"git commit -a -m wip", 4},
+		{"git commit -m 'two words'", 4},
+		{"git commit", 1},
+		{"git commit -am wip", 1},
+		{"   ", 0},
+	}
+	for _, tt := range tests {
+		if got := gradeAnswer(card, tt.answer); got != tt.want {
+			t.Errorf("gradeAnswer(%q) = %d, want %d", tt.answer, got, tt.want)
+		}
+	}
+
+	example := quizCard{Answer: "git log --oneline -5", Accept: []string{"git log --oneline -5"}, Syntax: "git log [options] [<path>...]"}
+	if got := gradeAnswer(example, "git log -5"); got != 3 {
+		t.Errorf("expected an answer fitting the syntax but not the example to get 3, got %d", got)
+	}
+}
+
+func TestSyntaxPattern(t *testing.T) {
+	tests := []struct {
+		syntax, command string
+		want            bool
+	}{
+		{"ls [dir]", "ls", true},
+		{"ls [dir]", "ls /tmp", true},
+		{"ls [dir]", "lsx", false},
+		{"cp [-r] <src> <dst>", "cp -r a b", true},
+		{"cp [-r] <src> <dst>", "cpa b", false},
+		{"git checkout {{ .branch }}", "git checkout main", true},
+		{"rm <file>...", "rm a b c", true},
+		{"tar -x[v]f <archive>", "tar -xvf a.tar", true},
+		{"tar -x[v]f <archive>", "tar -xf a.tar", true},
+	}
+	for _, tt := range tests {
+		if got := syntaxPattern(tt.syntax).MatchString(tt.command); got != tt.want {
+			t.Errorf("syntaxPattern(%q) matches %q = %v, want %v (%s)", tt.syntax, tt.command, got, tt.want, syntaxPattern(tt.syntax))
+		}
+	}
+}
+
+func TestQuizDeck(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	cards := []quizCard{{ID: "new"}, {ID: "later"}, {ID: "due"}, {ID: "overdue"}}
+	schedules := map[string]reviewState{
+		"later":   {Due: now.Add(time.Hour)},
+		"due":     {Due: now},
+		"overdue": {Due: now.AddDate(0, 0, -3)},
+	}
+	var ids []string
+	for _, card := range quizDeck(cards, schedules, now, 0) {
+		ids = append(ids, card.ID)
+	}
+	if want := []string{"overdue", "due", "new"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got deck %v, want %v", ids, want)
+	}
+	if deck := quizDeck(cards, schedules, now, 2); len(deck) != 2 {
+		t.Errorf("expected the deck limited to 2 cards, got %d", len(deck))
+	}
+}
+
+const quizSheetYAML = `title: Git
+commands:
+  - name: git status
+    shortDesc: Show the working tree status
+    syntax: git status [-s]
+    complexity: beginner
+    tags: [basics]
+  - name: git rebase
+    shortDesc: Reapply commits on top of another base
+    syntax: git rebase <upstream>
+    complexity: advanced
+    tags: [history]
+    examples:
+      - code: git rebase -i HEAD~3
+        description: Rework the last three commits
+`
+
+func TestRunQuiz(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "git.yaml")
+	if err := os.WriteFile(path, []byte(quizSheetYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	savedNow, savedInput := quizNow, quizInput
+	t.Cleanup(func() { quizNow, quizInput = savedNow, savedInput })
+	quizNow = func() time.Time { return now }
+
+	quizInput = strings.NewReader("git status\ngit rebase main\n\n")
+	var stdout, stderr bytes.Buffer
+	if status := runQuiz([]string{path}, &stdout, &stderr); status != 0 {
+		t.Fatalf("expected exit status 0, got %d (%s)", status, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"Git: 3 cards\n",
+		"[1/3] Show the working tree status\n> ✓ git status [-s]\n",
+		"[3/3] Rework the last three commits\n> ✗ git rebase -i HEAD~3\n",
+		"2 of 3 correct. Next review: 2024-03-02\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	// Nothing is due again the same day
+	stdout.Reset()
+	quizInput = strings.NewReader("")
+	runQuiz([]string{path}, &stdout, &stderr)
+	if got := stdout.String(); got != "Nothing due in Git. Next review: 2024-03-02\n" {
+		t.Errorf("got %q", got)
+	}
+
+	// The next day the cards come back, narrowed by tag and complexity
+	now = now.AddDate(0, 0, 1)
+	stdout.Reset()
+	quizInput = strings.NewReader("git status -s\n")
+	runQuiz([]string{"--tag", "basics", "--complexity", "beginner", path}, &stdout, &stderr)
+	if out := stdout.String(); !strings.Contains(out, "Git: 1 card\n") || !strings.Contains(out, "Next review: 2024-03-08") {
+		t.Errorf("expected only git status asked, got:\n%s", out)
+	}
+	state, err := loadQuizState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := state[annotationKey(CheatSheet{Path: path})]["git status"]; r.Repetitions != 2 || r.Interval != 6 {
+		t.Errorf("expected git status answered twice, got %+v", r)
+	}
+
+	stdout.Reset()
+	quizInput = strings.NewReader("\n4\n")
+	runQuiz([]string{"--reverse", "--limit", "1", path}, &stdout, &stderr)
+	if out := stdout.String(); !strings.Contains(out, "[1/1] git status [-s]\n(Enter shows the answer) → Show the working tree status\n") {
+		t.Errorf("expected the command shown and its description revealed, got:\n%s", out)
+	}
+
+	if status := runQuiz([]string{"--complexity", "expert", path}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected a usage error for an unknown complexity, got %d", status)
+	}
+}
@@ -3,12 +3,16 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/sirupsen/logrus"
 )
 
+const cheatsheetListTitle = "Available Cheatsheets"
+
 // Define styles
 var (
 	boxedViewportStyle = lipgloss.NewStyle().
@@ -66,13 +70,54 @@ var (
 	complexityStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#B5E8B5"))
 
+	complexityBadgeStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#1D1D1D")).
+				Padding(0, 1)
+
+	// Badge colors by complexity level, from green to red
+	complexityColors = map[complexityLevel]lipgloss.Color{
+		complexityBeginner:     lipgloss.Color("#5AF78E"),
+		complexityIntermediate: lipgloss.Color("#F3A922"),
+		complexityAdvanced:     lipgloss.Color("#FF6E6E"),
+	}
+
+	filterStatusStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#5AF78E"))
+
+	includedTagColor = lipgloss.Color("#5AF78E")
+	excludedTagColor = lipgloss.Color("#FF6E6E")
+
 	codeBlockStyle = lipgloss.NewStyle().
 			Background(lipgloss.Color("#282828")).
 			Foreground(lipgloss.Color("#B8BB26")).
 			Padding(0, 2)
+
+	deprecatedStyle = lipgloss.NewStyle().
+			Strikethrough(true).
+			Foreground(lipgloss.Color("#8A8A8A"))
+
+	deprecatedNoticeStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF8700")).
+				Bold(true)
+
+	outputStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#8A8A8A")).
+			Faint(true)
+
+	annotationStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#D7AFFF"))
+
+	missingStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#6C6C6C")).
+			Faint(true)
+
+	unresolvedVarStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF5555")).
+				Bold(true).
+				Underline(true)
 )
 
-func RenderTagMenu(tags []string, selectedIndex int, termWidth int) string {
+func RenderTagMenu(tags []string, selectedIndex int, termWidth int, filter tagFilter) string {
 
 	// Create a menu bar style
 	menuBarStyle := lipgloss.NewStyle().
@@ -80,7 +125,7 @@ func RenderTagMenu(tags []string, selectedIndex int, termWidth int) string {
 		Padding(0, 1)
 
 	// Get the visible tags with proper styling and indicators
-	visibleTags := GetVisibleTags(tags, selectedIndex, termWidth)
+	visibleTags := GetVisibleTags(tags, selectedIndex, termWidth, filter)
 
 	// Join them horizontally
 	menu := lipgloss.JoinHorizontal(lipgloss.Top, visibleTags...)
@@ -89,9 +134,33 @@ func RenderTagMenu(tags []string, selectedIndex int, termWidth int) string {
 	return menuBarStyle.Render(menu)
 }
 
+// Tag bar segment indices for the scroll indicators
+const (
+	tagScrollLeft  = -1
+	tagScrollRight = -2
+)
+
+// tagSegment is one rendered piece of the tag bar: a tag (index into the tag
+// slice) or one of the scroll indicators
+type tagSegment struct {
+	index int
+	text  string
+}
+
 // GetVisibleTags returns the visible tags for a menu based on the selected index and available width
 // It returns the slice of styled tag strings (including any scroll indicators)
-func GetVisibleTags(tags []string, selectedIndex, termWidth int) []string {
+func GetVisibleTags(tags []string, selectedIndex, termWidth int, filter tagFilter) []string {
+	segments := visibleTagSegments(tags, selectedIndex, termWidth, filter)
+	result := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		result = append(result, seg.text)
+	}
+	return result
+}
+
+// visibleTagSegments lays out the tag bar, keeping track of which tag each
+// styled segment belongs to so clicks can be mapped back onto it
+func visibleTagSegments(tags []string, selectedIndex, termWidth int, filter tagFilter) []tagSegment {
 	// Ensure the selected index is within bounds
 	if selectedIndex < 0 {
 		selectedIndex = 0
@@ -103,31 +172,29 @@ func GetVisibleTags(tags []string, selectedIndex, termWidth int) []string {
 	availableWidth := termWidth - 8 // Subtract some padding for the outer container
 
 	// We'll build our result as we go
-	var result []string
+	var result []tagSegment
 	var currentWidth int
 
 	// We'll track which tags we've added
 	addedTags := make(map[int]bool)
 
 	// Always include the selected tag first to ensure it's visible
-	selectedTagStyle := selectedCommandStyle.MarginRight(1)
-	selectedTag := selectedTagStyle.Render(tags[selectedIndex])
+	selectedTag := renderTag(tags[selectedIndex], true, filter)
 	selectedTagWidth := lipgloss.Width(selectedTag)
 
 	// Make sure we have room for the selected tag
 	if currentWidth+selectedTagWidth <= availableWidth {
-		result = append(result, selectedTag)
+		result = append(result, tagSegment{selectedIndex, selectedTag})
 		currentWidth += selectedTagWidth
 		addedTags[selectedIndex] = true
 	} else {
 		// If we can't even fit the selected tag, just return it alone
-		return []string{selectedTag}
+		return []tagSegment{{selectedIndex, selectedTag}}
 	}
 
 	// Now add tags to the left of the selected tag, starting from the closest one
 	for i := selectedIndex - 1; i >= 0; i-- {
-		normalTagStyle := normalCommandStyle.MarginRight(1)
-		styledTag := normalTagStyle.Render(tags[i])
+		styledTag := renderTag(tags[i], false, filter)
 		tagWidth := lipgloss.Width(styledTag)
 
 		if currentWidth+tagWidth > availableWidth {
@@ -135,22 +202,21 @@ func GetVisibleTags(tags []string, selectedIndex, termWidth int) []string {
 		}
 
 		// Insert at beginning to maintain left-to-right order
-		result = append([]string{styledTag}, result...)
+		result = append([]tagSegment{{i, styledTag}}, result...)
 		currentWidth += tagWidth
 		addedTags[i] = true
 	}
 
 	// Then add tags to the right of the selected tag
 	for i := selectedIndex + 1; i < len(tags); i++ {
-		normalTagStyle := normalCommandStyle.MarginRight(1)
-		styledTag := normalTagStyle.Render(tags[i])
+		styledTag := renderTag(tags[i], false, filter)
 		tagWidth := lipgloss.Width(styledTag)
 
 		if currentWidth+tagWidth > availableWidth {
 			break
 		}
 
-		result = append(result, styledTag)
+		result = append(result, tagSegment{i, styledTag})
 		currentWidth += tagWidth
 		addedTags[i] = true
 	}
@@ -163,7 +229,7 @@ func GetVisibleTags(tags []string, selectedIndex, termWidth int) []string {
 			Render("« ")
 
 		// Insert at the beginning
-		result = append([]string{leftIndicator}, result...)
+		result = append([]tagSegment{{tagScrollLeft, leftIndicator}}, result...)
 	}
 
 	// Add right scroll indicator if needed - only if the last tag isn't shown
@@ -173,12 +239,92 @@ func GetVisibleTags(tags []string, selectedIndex, termWidth int) []string {
 			Foreground(lipgloss.Color("#777777")).
 			Render(" »")
 
-		result = append(result, rightIndicator)
+		result = append(result, tagSegment{tagScrollRight, rightIndicator})
 	}
 
 	return result
 }
 
+// renderTag styles a single tag of the tag bar, marking tags the filter
+// includes with + and tags it excludes with -
+func renderTag(tag string, selected bool, filter tagFilter) string {
+	style := normalCommandStyle
+	if selected {
+		style = selectedCommandStyle
+	}
+	switch filter.State(tag) {
+	case tagIncluded:
+		return style.Foreground(includedTagColor).MarginRight(1).Render("+" + tag)
+	case tagExcluded:
+		return style.Foreground(excludedTagColor).Strikethrough(true).MarginRight(1).Render("-" + tag)
+	default:
+		return style.MarginRight(1).Render(tag)
+	}
+}
+
+// RenderFilterStatus renders the line under the tags describing the active
+// tag filter expression and any applied search
+func RenderFilterStatus(filter commandFilter, sorted bool, query string, searchActive bool, results int, termWidth int) string {
+	status := "Tags: " + filter.tags.String()
+	if len(filter.tags.include) > 1 {
+		if filter.tags.mode == matchAll {
+			status += " [all of]"
+		} else {
+			status += " [any of]"
+		}
+	}
+	if filter.complexity.level != complexityUnset {
+		status += " • Complexity: " + filter.complexity.String()
+	}
+	if sorted {
+		status += " • Sorted by complexity"
+	}
+	if filter.platform != "" {
+		status += " • Platform: " + filter.platform
+	}
+	if filter.versions != nil {
+		status += " • Installed versions"
+	}
+	if filter.available {
+		status += " • Installed only"
+	}
+	if searchActive {
+		status += fmt.Sprintf(" • Search: %s", query)
+	}
+	status += fmt.Sprintf(" (%d results)", results)
+
+	if w := termWidth - 8; w > 0 {
+		status = ansi.Truncate(status, w, "…")
+	}
+	return lipgloss.NewStyle().
+		Width(termWidth-4).
+		Padding(0, 1).
+		Render(filterStatusStyle.Render(status))
+}
+
+// tagMenuLeftInset is the number of columns between the left edge of the
+// boxed tag bar and its first segment (border, box padding, menu padding)
+func tagMenuLeftInset() int {
+	return boxedViewportStyle.GetBorderLeftSize() + boxedViewportStyle.GetPaddingLeft() + 1
+}
+
+// TagAtColumn returns the tag bar segment under screen column x: a tag index,
+// tagScrollLeft or tagScrollRight. ok is false when x falls between segments.
+func TagAtColumn(tags []string, selectedIndex, termWidth int, filter tagFilter, x int) (index int, ok bool) {
+	if len(tags) == 0 {
+		return 0, false
+	}
+	col := tagMenuLeftInset()
+	for _, seg := range visibleTagSegments(tags, selectedIndex, termWidth, filter) {
+		w := lipgloss.Width(seg.text)
+		if x >= col && x < col+w {
+			return seg.index, true
+		}
+		col += w
+	}
+	return 0, false
+}
+
 // RenderCommandList renders a styled list of commands with the current selection highlighted
 func RenderCommandList(description string, commands []Command, selectedIdx int) string {
 	var b strings.Builder
@@ -189,57 +335,213 @@ func RenderCommandList(description string, commands []Command, selectedIdx int)
 
 	// Commands
 	for i, cmd := range commands {
-		// Format the command number
-		cmdNum := commandNumberStyle.Render(fmt.Sprintf("%d.", i+1))
+		b.WriteString(renderCommandRow(i, cmd, i == selectedIdx))
+		b.WriteString("\n\n")
+	}
 
-		// Format the command name and description
-		cmdText := fmt.Sprintf("%s %s - %s", cmdNum, cmd.Name, cmd.ShortDesc)
+	return b.String()
+}
 
-		// Apply the appropriate style based on whether this is the selected command
-		var styledCmd string
-		if i == selectedIdx {
-			styledCmd = selectedCommandStyle.Render(cmdText)
-		} else {
-			styledCmd = normalCommandStyle.Render(cmdText)
-		}
+// renderCommandRow renders the single list line for the command at index i
+func renderCommandRow(i int, cmd Command, selected bool) string {
+	// Format the command number
+	cmdNum := commandNumberStyle.Render(fmt.Sprintf("%d.", i+1))
 
-		b.WriteString(styledCmd)
+	// Format the command name and description, striking deprecated ones out
+	name := cmd.Name
+	if cmd.Deprecated != nil {
+		name = deprecatedStyle.Render(name)
+	}
+	cmdText := fmt.Sprintf("%s %s - %s", cmdNum, name, cmd.ShortDesc)
 
-		// Add tags if present
-		if len(cmd.Tags) > 0 {
-			tags := fmt.Sprintf(" [%s]", strings.Join(cmd.Tags, ", "))
-			b.WriteString(tagStyle.Render(tags))
-		}
+	// Apply the appropriate style based on whether this is the selected command
+	var styledCmd string
+	if selected {
+		styledCmd = selectedCommandStyle.Render(cmdText)
+	} else {
+		styledCmd = normalCommandStyle.Render(cmdText)
+	}
 
-		b.WriteString("\n\n")
+	// Add a complexity badge and tags if present
+	if badge := renderComplexityBadge(cmd.Complexity); badge != "" {
+		styledCmd += " " + badge
 	}
+	if len(cmd.Tags) > 0 {
+		tags := fmt.Sprintf(" [%s]", strings.Join(cmd.Tags, ", "))
+		styledCmd += tagStyle.Render(tags)
+	}
+	if len(cmd.Missing) > 0 {
+		styledCmd += missingStyle.Render(" ✗ not installed: " + strings.Join(cmd.Missing, ", "))
+	}
+
+	return styledCmd
+}
+
+// maxOutputLines is how many lines of an example's output the detail view
+// shows before it is expanded
+const maxOutputLines = 6
 
+// outputLines counts the lines of an example output
+func outputLines(output string) int {
+	if output == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimRight(output, "\n"), "\n") + 1
+}
+
+// renderOutput renders what an example prints and the status it exits with
+// as a dim block under its code, ▸ marking a block cut short and ▾ one shown
+// in full
+func renderOutput(ex Example, expand bool) string {
+	if ex.Output == "" && ex.ExitCode == 0 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(ex.Output, "\n"), "\n")
+	if ex.Output == "" {
+		lines = nil
+	}
+	hidden := 0
+	if !expand && len(lines) > maxOutputLines {
+		hidden = len(lines) - maxOutputLines
+		lines = lines[:maxOutputLines]
+	}
+
+	header := "▾ Output"
+	if hidden > 0 {
+		header = "▸ Output"
+	}
+	if ex.Output == "" {
+		header = "Output"
+	}
+	if ex.ExitCode != 0 {
+		header += fmt.Sprintf(" (exit %d)", ex.ExitCode)
+	}
+
+	var b strings.Builder
+	b.WriteString("    " + outputStyle.Render(header) + "\n")
+	for _, line := range lines {
+		b.WriteString("    " + outputStyle.Render("│ "+line) + "\n")
+	}
+	if hidden > 0 {
+		b.WriteString("    " + outputStyle.Render(fmt.Sprintf("│ … %s hidden (O to expand)", plural(hidden, "line"))) + "\n")
+	}
 	return b.String()
 }
 
-// RenderCheatsheetList renders a styled list of cheatsheet files with the current selection highlighted
-func RenderCheatsheetList(cheatsheets []string, selectedIdx int) string {
+// renderComplexityBadge renders a short color-coded badge for a complexity
+// level, or nothing when the command doesn't declare a known one
+func renderComplexityBadge(complexity string) string {
+	level, _ := parseComplexity(complexity)
+	if level == complexityUnset {
+		return ""
+	}
+	return complexityBadgeStyle.
+		Background(complexityColors[level]).
+		Render(level.String()[:3])
+}
+
+// RenderCommandWindow renders the part of RenderCommandList's output that
+// starts at content line offset and fits in a width x height box. Only the
+// commands that land inside the window are styled, so the cost doesn't grow
+// with the size of the sheet.
+func RenderCommandWindow(description string, commands []Command, selectedIdx, offset, height, width int) string {
+	if height <= 0 {
+		return ""
+	}
+
+	descLines := strings.Split(description, "\n")
+	lines := make([]string, 0, height)
+	for line := offset; line < offset+height; line++ {
+		var s string
+		if line < len(descLines) {
+			s = descLines[line]
+		} else if idx, ok := CommandAtLine(description, len(commands), line); ok {
+			s = renderCommandRow(idx, commands[idx], idx == selectedIdx)
+		}
+		if width > 0 {
+			s = ansi.Truncate(s, width, "")
+		}
+		lines = append(lines, s)
+	}
+
+	box := lipgloss.NewStyle().Height(height).MaxHeight(height)
+	if width > 0 {
+		box = box.Width(width).MaxWidth(width)
+	}
+	return box.Render(strings.Join(lines, "\n"))
+}
+
+// commandListLines returns the number of content lines RenderCommandList
+// produces for n commands
+func commandListLines(description string, n int) int {
+	return commandListLine(description, n)
+}
+
+// commandListLine returns the content line on which RenderCommandList draws
+// the command at idx: the description, a blank line, then one command every
+// other line
+func commandListLine(description string, idx int) int {
+	return lipgloss.Height(description) + 1 + 2*idx
+}
+
+// CommandAtLine maps a content line of RenderCommandList back to the index of
+// the command drawn on it
+func CommandAtLine(description string, count, line int) (int, bool) {
+	return listItemAtLine(commandListLine(description, 0), count, line)
+}
+
+// listItemAtLine maps a content line back to an item of a list that starts at
+// first and puts a blank line between items
+func listItemAtLine(first, count, line int) (int, bool) {
+	offset := line - first
+	if offset < 0 || offset%2 != 0 || offset/2 >= count {
+		return 0, false
+	}
+	return offset / 2, true
+}
+
+// RenderCheatsheetList renders a styled list of cheatsheet files and their
+// groups with the current selection highlighted
+func RenderCheatsheetList(rows []selectorRow, selectedIdx int, query string) string {
 	var b strings.Builder
 
 	// Title
-	b.WriteString(headingStyle.Render("Available Cheatsheets"))
+	b.WriteString(headingStyle.Render(cheatsheetListTitle))
 	b.WriteString("\n\n")
 
 	// Handle empty list
-	if len(cheatsheets) == 0 {
+	if len(rows) == 0 && query != "" {
+		b.WriteString(noteStyle.Render(fmt.Sprintf("No cheatsheets match %q.", query)))
+		return b.String()
+	}
+	if len(rows) == 0 {
 		b.WriteString(noteStyle.Render("No cheatsheets available in the directory."))
 		b.WriteString("\n\n")
 		b.WriteString("Place .yaml cheatsheet files in the cheatsheets directory.")
 		return b.String()
 	}
 
-	// Cheatsheet list
-	for i, cheatsheet := range cheatsheets {
-		// Format the cheatsheet number
-		cheatsheetNum := commandNumberStyle.Render(fmt.Sprintf("%d.", i+1))
+	// Cheatsheet list; only sheets are numbered
+	sheetNum := 0
+	for i, row := range rows {
+		indent := strings.Repeat("  ", row.depth)
+
+		var cheatsheetText string
+		if row.isGroup() {
+			marker := "▾"
+			if row.collapsed {
+				marker = "▸"
+			}
+			cheatsheetText = fmt.Sprintf("%s%s %s %s", indent, marker, row.label,
+				noteStyle.Render(fmt.Sprintf("(%d)", len(row.members))))
+		} else {
+			sheetNum++
+			// Format the cheatsheet number
+			cheatsheetNum := commandNumberStyle.Render(fmt.Sprintf("%d.", sheetNum))
 
-		// Format the cheatsheet name
-		cheatsheetText := fmt.Sprintf("%s %s", cheatsheetNum, cheatsheet)
+			// Format the cheatsheet name
+			cheatsheetText = fmt.Sprintf("%s%s %s", indent, cheatsheetNum, row.label)
+		}
 
 		// Apply the appropriate style based on whether this is the selected cheatsheet
 		var styledCheatsheet string
@@ -256,8 +558,85 @@ func RenderCheatsheetList(cheatsheets []string, selectedIdx int) string {
 	return b.String()
 }
 
-// RenderCommandDetail renders styled details for a command with enhanced formatting
-func RenderCommandDetail(cmd Command) string {
+// RenderSheetPreview renders the preview pane of the cheatsheet selector: the
+// sheet's title, description, command count and most used tags
+func RenderSheetPreview(info sheetInfo, width, height int) string {
+	var b strings.Builder
+
+	if info.Err != nil {
+		b.WriteString(headingStyle.Render(info.Name()))
+		b.WriteString("\n")
+		b.WriteString(noteStyle.Render(fmt.Sprintf("Failed to load: %v", info.Err)))
+	} else {
+		title := info.Title
+		if title == "" {
+			title = info.Name()
+		}
+		b.WriteString(headingStyle.Render(title))
+		b.WriteString("\n")
+		if info.Category != "" {
+			b.WriteString(tagStyle.Render(info.Category))
+			b.WriteString("\n\n")
+		}
+		if info.Description != "" {
+			b.WriteString(info.Description)
+			b.WriteString("\n\n")
+		}
+		b.WriteString(optionFlagStyle.Render(fmt.Sprintf("%d commands", info.CommandCount)))
+		b.WriteString("\n\n")
+		if len(info.TopTags) > 0 {
+			b.WriteString(fmt.Sprintf("Top tags: %s", tagStyle.Render(strings.Join(info.TopTags, ", "))))
+			b.WriteString("\n")
+		}
+	}
+
+	// Keep the box within the space the selector leaves for it
+	return boxedViewportStyle.
+		Width(max(0, width-boxedViewportStyle.GetHorizontalBorderSize())).
+		MaxHeight(max(0, height)).
+		Render(strings.TrimRight(b.String(), "\n"))
+}
+
+// RenderGroupPreview renders the preview pane for a group row of the
+// cheatsheet selector: the group's name and the sheets inside it
+func RenderGroupPreview(row selectorRow, width, height int) string {
+	var b strings.Builder
+
+	b.WriteString(headingStyle.Render(row.label))
+	b.WriteString("\n")
+	b.WriteString(optionFlagStyle.Render(fmt.Sprintf("%d cheatsheets", len(row.members))))
+	b.WriteString("\n\n")
+	for _, info := range row.members {
+		title := info.Title
+		if title == "" {
+			title = info.Name()
+		}
+		b.WriteString(fmt.Sprintf("• %s\n", title))
+	}
+
+	return boxedViewportStyle.
+		Width(max(0, width-boxedViewportStyle.GetHorizontalBorderSize())).
+		MaxHeight(max(0, height)).
+		Render(strings.TrimRight(b.String(), "\n"))
+}
+
+// cheatsheetListLine returns the content line on which RenderCheatsheetList
+// draws the row at idx
+func cheatsheetListLine(idx int) int {
+	return lipgloss.Height(headingStyle.Render(cheatsheetListTitle)) + 1 + 2*idx
+}
+
+// CheatsheetAtLine maps a content line of RenderCheatsheetList back to the
+// index of the row drawn on it
+func CheatsheetAtLine(count, line int) (int, bool) {
+	return listItemAtLine(cheatsheetListLine(0), count, line)
+}
+
+// RenderCommandDetail renders styled details for a command with enhanced
+// formatting. Variables the command was expanded with and left unresolved are
+// highlighted. Example outputs longer than maxOutputLines are cut short unless
+// expandOutput is set.
+func RenderCommandDetail(cmd Command, expandOutput bool) string {
 	var b strings.Builder
 
 	// Description with nice styling
@@ -267,14 +646,18 @@ func RenderCommandDetail(cmd Command) string {
 	// Syntax with nice code block styling
 	b.WriteString("Syntax: ")
 	b.WriteString("\n")
-	b.WriteString(codeBlockStyle.Render(cmd.Syntax))
+	b.WriteString(renderVars(cmd.Syntax, codeBlockStyle))
 	b.WriteString("\n\n")
 
 	// Complexity with color coding
 	if cmd.Complexity != "" {
 		complexity := cmd.Complexity
 		complexityText := fmt.Sprintf("Complexity: %s", complexity)
-		b.WriteString(complexityStyle.Render(complexityText))
+		style := complexityStyle
+		if level, ok := parseComplexity(complexity); ok {
+			style = style.Foreground(complexityColors[level])
+		}
+		b.WriteString(style.Render(complexityText))
 		b.WriteString("\n\n")
 	}
 
@@ -285,6 +668,47 @@ func RenderCommandDetail(cmd Command) string {
 		b.WriteString("\n\n")
 	}
 
+	// Other names and search keywords
+	if len(cmd.Aliases) > 0 || len(cmd.Keywords) > 0 {
+		if len(cmd.Aliases) > 0 {
+			b.WriteString(noteStyle.Render("Also known as: " + strings.Join(cmd.Aliases, ", ")))
+			b.WriteString("\n")
+		}
+		if len(cmd.Keywords) > 0 {
+			b.WriteString(noteStyle.Render("Keywords: " + strings.Join(cmd.Keywords, ", ")))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Where and with which versions the command works
+	if len(cmd.Platforms) > 0 || versionRange(cmd) != "" {
+		if len(cmd.Platforms) > 0 {
+			b.WriteString(noteStyle.Render("Platforms: " + strings.Join(cmd.Platforms, ", ")))
+			b.WriteString("\n")
+		}
+		if requires := versionRange(cmd); requires != "" {
+			b.WriteString(noteStyle.Render("Requires: " + requires))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Deprecation, pointing at the command to use instead
+	if d := cmd.Deprecated; d != nil {
+		text := "Deprecated"
+		if d.Since != "" {
+			text += " since " + d.Since
+		}
+		b.WriteString(deprecatedNoticeStyle.Render(text))
+		if d.Replacement != "" {
+			b.WriteString(" → use ")
+			b.WriteString(syntaxStyle.Render(d.Replacement))
+			b.WriteString(noteStyle.Render(" (r to open)"))
+		}
+		b.WriteString("\n\n")
+	}
+
 	// Options
 	if len(cmd.Options) > 0 {
 		b.WriteString(headingStyle.Render("Options:"))
@@ -306,8 +730,9 @@ func RenderCommandDetail(cmd Command) string {
 		for i, ex := range cmd.Examples {
 			b.WriteString(fmt.Sprintf("  Example %d:\n", i+1))
 			b.WriteString("  ")
-			b.WriteString(codeBlockStyle.Render(fmt.Sprintf("$ %s", ex.Code)))
+			b.WriteString(renderVars(fmt.Sprintf("$ %s", ex.Code), codeBlockStyle))
 			b.WriteString("\n")
+			b.WriteString(renderOutput(ex, expandOutput))
 			b.WriteString(fmt.Sprintf("    %s", ex.Description))
 			b.WriteString("\n\n")
 		}
@@ -319,12 +744,36 @@ func RenderCommandDetail(cmd Command) string {
 		b.WriteString("\n")
 		for _, note := range cmd.Notes {
 			noteLine := fmt.Sprintf("  • %s",
-				noteStyle.Render(note))
+				renderVars(note, noteStyle))
 			b.WriteString(noteLine)
 			b.WriteString("\n")
 		}
 	}
 
+	// The user's own notes, set apart from what the sheet says
+	if a := cmd.Annotation; a != nil {
+		b.WriteString("\n")
+		b.WriteString(headingStyle.Render("Your Notes:"))
+		b.WriteString("\n")
+		if len(a.Tags) > 0 {
+			b.WriteString(annotationStyle.Render("  Tags: " + strings.Join(a.Tags, ", ")))
+			b.WriteString("\n")
+		}
+		for _, ex := range a.Examples {
+			b.WriteString("  ")
+			b.WriteString(annotationStyle.Render("$ " + ex.Code))
+			b.WriteString("\n")
+			if ex.Description != "" {
+				b.WriteString(annotationStyle.Render("    " + ex.Description))
+				b.WriteString("\n")
+			}
+		}
+		for _, note := range a.Notes {
+			b.WriteString(annotationStyle.Render("  • " + note))
+			b.WriteString("\n")
+		}
+	}
+
 	// Related commands
 	if len(cmd.Related) > 0 {
 		b.WriteString("\n")
@@ -341,9 +790,138 @@ func RenderCommandDetail(cmd Command) string {
 		b.WriteString("\n")
 	}
 
+	// Where the command came from, on sheets built with extends or include
+	if cmd.Source != "" || cmd.Overrides != "" {
+		b.WriteString("\n")
+		if cmd.Source != "" {
+			b.WriteString(noteStyle.Render("From: " + cmd.Source))
+			b.WriteString("\n")
+		}
+		if cmd.Overrides != "" {
+			b.WriteString(noteStyle.Render("Overrides: " + cmd.Overrides))
+			b.WriteString("\n")
+		}
+	}
+
 	return b.String()
 }
 
+// RenderEditError renders why an edited sheet failed to reload
+func RenderEditError(path string, err error, width int) string {
+	var b strings.Builder
+	b.WriteString(headingStyle.Render("The edited cheatsheet has errors"))
+	b.WriteString("\n\n")
+	b.WriteString(noteStyle.Render(path))
+	b.WriteString("\n\n")
+	b.WriteString(err.Error())
+	b.WriteString("\n\n")
+	b.WriteString("Press e to fix it, or Esc to keep the version loaded before editing.")
+	return lipgloss.NewStyle().Width(max(0, width)).Render(b.String())
+}
+
+// RenderAnnotationError renders why an edited annotation couldn't be saved
+func RenderAnnotationError(path string, err error, width int) string {
+	var b strings.Builder
+	b.WriteString(headingStyle.Render("The edited annotation has errors"))
+	b.WriteString("\n\n")
+	b.WriteString(noteStyle.Render(path))
+	b.WriteString("\n\n")
+	b.WriteString(err.Error())
+	b.WriteString("\n\n")
+	b.WriteString("Press e to fix it, or Esc to keep the annotation saved before editing.")
+	return lipgloss.NewStyle().Width(max(0, width)).Render(b.String())
+}
+
+// formLabelWidth is the width of the label column of the command form
+const formLabelWidth = 24
+
+// RenderCommandForm renders the add/edit command form, scrolled so the field
+// under the cursor stays within height lines
+func RenderCommandForm(f commandForm, height, width int) string {
+	caret := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Background(lipgloss.Color("#5AF78E")).Render(" ")
+	labelStyle := commandNumberStyle.Width(formLabelWidth)
+
+	title := "Add command"
+	if f.original != "" {
+		title = fmt.Sprintf("Edit %s", f.original)
+		if f.cmd.Source != "" {
+			title += fmt.Sprintf(" in %s", filepath.Base(f.path))
+		}
+	}
+	lines := []string{headingStyle.UnsetPaddingBottom().Render(title), ""}
+	cursorLine := 0
+
+	for i, field := range f.fields() {
+		selected := i == f.cursor
+		var value string
+		switch field.kind {
+		case fieldText:
+			value = f.text(field)
+			if selected {
+				value += caret
+			}
+		case fieldComplexity:
+			value = renderComplexityBadge(f.cmd.Complexity)
+			if value == "" {
+				value = noteStyle.Render("none")
+			}
+			if selected {
+				value = "‹ " + value + " ›"
+			}
+		case fieldPicker:
+			var items []string
+			for _, item := range f.picked(field) {
+				items = append(items, tagStyle.Render(item))
+			}
+			value = strings.Join(items, ", ")
+			if selected {
+				if value != "" {
+					value += ", "
+				}
+				value += f.input + caret
+			}
+		case fieldAdd:
+			value = optionFlagStyle.Render(field.label)
+		}
+
+		label := field.label
+		if field.kind == fieldAdd {
+			label = ""
+		}
+		row := labelStyle.Render(label) + value
+		if selected {
+			cursorLine = len(lines)
+			row = selectedCommandStyle.Render(row)
+		} else {
+			row = normalCommandStyle.Render(row)
+		}
+		lines = append(lines, ansi.Truncate(row, max(0, width), "…"))
+
+		if selected && field.kind == fieldPicker {
+			if suggestions := f.suggestions(); len(suggestions) > 0 {
+				hint := strings.Repeat(" ", formLabelWidth+1) + noteStyle.Render("Tab: "+strings.Join(suggestions, " · "))
+				lines = append(lines, ansi.Truncate(hint, max(0, width), "…"))
+			}
+		}
+	}
+
+	if f.err != nil {
+		lines = append(lines, "", lipgloss.NewStyle().Foreground(excludedTagColor).Render(f.err.Error()))
+	}
+
+	// Keep the cursor in view
+	if height > 0 && len(lines) > height {
+		offset := scrollOffsetFor(cursorLine, 0, height)
+		if f.err != nil {
+			// The error is worth more than the title
+			offset = max(offset, len(lines)-height)
+			offset = min(offset, cursorLine)
+		}
+		lines = lines[offset:min(len(lines), offset+height)]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // RenderSearchBar renders the search input bar
 func RenderSearchBar(query string, width int) string {
 	searchStyle := lipgloss.NewStyle().
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+)
+
+// searchIndex holds the lowercased search text of every command of a sheet,
+// computed once at load time so live search doesn't lowercase the whole sheet
+// on every keystroke
+type searchIndex struct {
+	commands []Command
+	text     []string
+}
+
+// newSearchIndex builds the search index for commands. A command is found by
+// its name, aliases and keywords, one per line so a query can't match across
+// them.
+func newSearchIndex(commands []Command) searchIndex {
+	idx := searchIndex{
+		commands: commands,
+		text:     make([]string, len(commands)),
+	}
+	for i, cmd := range commands {
+		names := append([]string{cmd.Name}, cmd.Aliases...)
+		idx.text[i] = strings.ToLower(strings.Join(append(names, cmd.Keywords...), "\n"))
+	}
+	return idx
+}
+
+// Filter returns the commands that pass filter and whose name, aliases or
+// keywords contain query, ignoring case
+func (idx searchIndex) Filter(query string, filter commandFilter) []Command {
+	// Collect matching positions first so the result is allocated once, and
+	// not at all when every command matches
+	lowerQuery := strings.ToLower(query)
+	var matches []int
+	for i, text := range idx.text {
+		if strings.Contains(text, lowerQuery) && filter.Matches(idx.commands[i]) {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == len(idx.commands) {
+		return idx.commands
+	}
+
+	filtered := make([]Command, len(matches))
+	for i, m := range matches {
+		filtered[i] = idx.commands[m]
+	}
+	return filtered
+}
+
+// findCommand finds the command called name among commands, by its name or
+// else by one of its aliases
+func findCommand(commands []Command, name string) (Command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	for _, cmd := range commands {
+		if containsString(cmd.Aliases, name) {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// resolveRelated returns cmd with the related commands given by an alias
+// named by the command the alias stands for
+func resolveRelated(cmd Command, commands []Command) Command {
+	related := make([]string, len(cmd.Related))
+	for i, name := range cmd.Related {
+		related[i] = name
+		if other, ok := findCommand(commands, name); ok {
+			related[i] = other.Name
+		}
+	}
+	if len(related) > 0 {
+		cmd.Related = related
+	}
+	return cmd
+}
+
+func filterCommandsBySearch(commands []Command, query string) []Command {
+	return newSearchIndex(commands).Filter(query, commandFilter{})
+}
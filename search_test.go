@@ -1,7 +1,12 @@
 package main
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 )
 
 func TestFilterCommandsBySearch(t *testing.T) {
@@ -62,3 +67,94 @@ func TestFilterCommandsBySearch(t *testing.T) {
 		t.Errorf("Expected 'kubectl get pods', got '%s'", result[0].Name)
 	}
 }
+
+func TestSearchAliasesAndKeywords(t *testing.T) {
+	commands := []Command{
+		{Name: "git reset", Aliases: []string{"uncommit"}, Keywords: []string{"undo commit", "unstage"}},
+		{Name: "git commit"},
+		{Name: "git revert", Keywords: []string{"undo a pushed commit"}},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"uncommit", []string{"git reset"}},
+		{"Undo Commit", []string{"git reset"}},
+		{"undo", []string{"git reset", "git revert"}},
+		{"commit", []string{"git reset", "git commit", "git revert"}},
+		{"reset uncommit", nil}, // a query doesn't run from the name into an alias
+	}
+	for _, tt := range tests {
+		if got := commandNames(filterCommandsBySearch(commands, tt.query)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFindCommandByAlias(t *testing.T) {
+	commands := []Command{
+		{Name: "git reset", Aliases: []string{"uncommit", "git unstage"}},
+		{Name: "git restore", Aliases: []string{"git reset"}},
+		{Name: "git log", Related: []string{"uncommit", "git show"}},
+	}
+	if cmd, ok := findCommand(commands, "git reset"); !ok || cmd.Name != "git reset" {
+		t.Errorf("expected a name to win over an alias, got %q", cmd.Name)
+	}
+	if cmd, ok := findCommand(commands, "uncommit"); !ok || cmd.Name != "git reset" {
+		t.Errorf("expected uncommit to find git reset, got %q", cmd.Name)
+	}
+	if _, ok := findCommand(commands, "undo"); ok {
+		t.Error("expected no command called undo")
+	}
+
+	cmd := resolveRelated(commands[2], commands)
+	if want := []string{"git reset", "git show"}; !reflect.DeepEqual(cmd.Related, want) {
+		t.Errorf("resolveRelated() = %v, want %v", cmd.Related, want)
+	}
+	if commands[2].Related[0] != "uncommit" {
+		t.Error("resolveRelated changed the sheet's command")
+	}
+}
+
+func TestAliasesInDetailView(t *testing.T) {
+	sheet := CheatSheet{Title: "Git", Commands: []Command{
+		{Name: "git log", ShortDesc: "show history", Related: []string{"uncommit"}},
+		{Name: "git reset", ShortDesc: "move HEAD", Aliases: []string{"uncommit"}, Keywords: []string{"undo commit"}},
+	}}
+	var tm tea.Model = initialModel("", "cheatsheets")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(cheatSheetLoadedMsg(sheet))
+	m := press(tm.(model), tea.KeyMsg{Type: tea.KeyEnter})
+	if detail := ansi.Strip(m.viewport.View()); !strings.Contains(detail, "git reset") {
+		t.Errorf("expected the related alias shown as git reset, got:\n%s", detail)
+	}
+
+	m = press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = press(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	detail := ansi.Strip(m.viewport.View())
+	for _, want := range []string{"Also known as: uncommit", "Keywords: undo commit"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected %q in:\n%s", want, detail)
+		}
+	}
+}
+
+func TestLintAliases(t *testing.T) {
+	sheet := CheatSheet{Commands: []Command{
+		{Name: "git reset", Aliases: []string{"uncommit", "git restore"}},
+		{Name: "git restore"},
+		{Name: "git revert", Aliases: []string{"uncommit", "undo"}},
+		{Name: "git checkout", Deprecated: &Deprecation{Replacement: "undo"}},
+	}}
+	want := []string{
+		`command "git reset": alias "git restore" is the name of a command`,
+		`command "git revert": alias "uncommit" is also an alias of command "git reset"`,
+	}
+	if got := lintAliases(sheet); !reflect.DeepEqual(got, want) {
+		t.Errorf("lintAliases() = %q, want %q", got, want)
+	}
+	if got := lintDeprecated(sheet); got != nil {
+		t.Errorf("expected the replacement found by its alias, got %q", got)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// previewMinWidth is the narrowest terminal that still gets a preview pane
+// next to the cheatsheet list
+const previewMinWidth = 80
+
+// topTagCount is how many of a sheet's most used tags the preview lists
+const topTagCount = 5
+
+// sheetInfo is what the selector knows about a cheatsheet file: enough to
+// search, group and preview it without keeping every command around
+type sheetInfo struct {
+	Root         string // cheatsheet root the sheet was found in
+	Path         string // relative to Root
+	Document     int    // index of the sheet within its file
+	Documents    int    // number of sheets in the file
+	Title        string
+	Description  string
+	Category     string
+	CommandCount int
+	TopTags      []string
+	Err          error // set when the sheet failed to load
+}
+
+// loadSheetInfos loads the metadata of every cheatsheet in paths, several
+// files at a time. Files holding several sheets get an entry per sheet. A
+// file that fails to load is kept with its error so the selector can still
+// list it.
+func loadSheetInfos(dir string, paths []string) []sheetInfo {
+	perFile := make([][]sheetInfo, len(paths))
+
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for i, path := range paths {
+		g.Go(func() error {
+			sheets, err := LoadCheatSheets(filepath.Join(dir, path))
+			if err != nil {
+				perFile[i] = []sheetInfo{{Root: dir, Path: path, Documents: 1, Err: err}}
+				return nil
+			}
+			for _, sheet := range sheets {
+				perFile[i] = append(perFile[i], sheetInfo{
+					Root:         dir,
+					Path:         path,
+					Document:     sheet.Document,
+					Documents:    len(sheets),
+					Title:        sheet.Title,
+					Description:  sheet.Description,
+					Category:     sheet.Category,
+					CommandCount: len(sheet.Commands),
+					TopTags:      topTags(sheet.Commands, topTagCount),
+				})
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	var infos []sheetInfo
+	for _, file := range perFile {
+		infos = append(infos, file...)
+	}
+	return infos
+}
+
+// Name returns the sheet's path, followed by its document number when its
+// file holds several sheets
+func (info sheetInfo) Name() string {
+	if info.Documents > 1 {
+		return fmt.Sprintf("%s#%d", info.Path, info.Document+1)
+	}
+	return info.Path
+}
+
+// topTags returns up to n of the tags used by the most commands, most used
+// first and alphabetically among equals
+func topTags(commands []Command, n int) []string {
+	counts := make(map[string]int)
+	for _, cmd := range commands {
+		for _, tag := range cmd.Tags {
+			counts[tag]++
+		}
+	}
+
+	var tags []string
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	if len(tags) > n {
+		tags = tags[:n]
+	}
+	return tags
+}
+
+// filterSheets returns the sheets whose file name, title, category or
+// description contains query, ignoring case
+func filterSheets(infos []sheetInfo, query string) []sheetInfo {
+	if query == "" {
+		return infos
+	}
+
+	var filtered []sheetInfo
+	lowerQuery := strings.ToLower(query)
+	for _, info := range infos {
+		text := strings.ToLower(strings.Join([]string{info.Path, info.Title, info.Category, info.Description}, "\n"))
+		if strings.Contains(text, lowerQuery) {
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered
+}
+
+// applySheetFilter narrows the selector list to the sheets matching the
+// selector search and moves the cursor back to the top
+func (m *model) applySheetFilter() {
+	m.cheatsheets = filterSheets(m.allCheatsheets, m.sheetQuery)
+	m.selectorRows = buildSelectorRows(m.cheatsheets, m.selectorGrouping, m.collapsedGroups, m.sheetQuery != "")
+	m.viewport.GotoTop()
+	m.selectCheatsheet(0)
+}
+
+// rebuildSelectorRows lays the selector list out again after a group was
+// collapsed or the grouping changed, keeping the cursor on the same row
+func (m *model) rebuildSelectorRows() {
+	var current string
+	if m.currentCheatsheet < len(m.selectorRows) {
+		current = m.selectorRows[m.currentCheatsheet].key()
+	}
+	m.selectorRows = buildSelectorRows(m.cheatsheets, m.selectorGrouping, m.collapsedGroups, m.sheetQuery != "")
+
+	idx := 0
+	for i, row := range m.selectorRows {
+		if row.key() == current {
+			idx = i
+			break
+		}
+	}
+	m.selectCheatsheet(idx)
+}
+
+// setGroupCollapsed collapses or expands the group row at idx and remembers
+// the choice for the next session
+func (m *model) setGroupCollapsed(idx int, collapsed bool) {
+	row := m.selectorRows[idx]
+	if !row.isGroup() || row.collapsed == collapsed || m.sheetQuery != "" {
+		return
+	}
+	if collapsed {
+		m.collapsedGroups[row.group] = true
+	} else {
+		delete(m.collapsedGroups, row.group)
+	}
+	m.currentCheatsheet = idx
+	m.rebuildSelectorRows()
+	m.saveUIState()
+}
+
+// parentGroup returns the index of the group row containing the row at idx,
+// or -1 for top level rows
+func (m model) parentGroup(idx int) int {
+	depth := m.selectorRows[idx].depth
+	for i := idx - 1; i >= 0; i-- {
+		if row := m.selectorRows[i]; row.isGroup() && row.depth < depth {
+			return i
+		}
+	}
+	return -1
+}
+
+// activateSelectorRow opens the sheet under the cursor, or collapses or
+// expands the group under it
+func (m *model) activateSelectorRow() tea.Cmd {
+	if len(m.selectorRows) == 0 {
+		return nil
+	}
+	row := m.selectorRows[m.currentCheatsheet]
+	if row.isGroup() {
+		m.setGroupCollapsed(m.currentCheatsheet, !row.collapsed)
+		return nil
+	}
+	return m.loadSelectedCheatsheet()
+}
+
+// restoreUIState applies the state saved by a previous session
+func (m *model) restoreUIState(state uiState) {
+	m.selectorGrouping = parseSelectorGrouping(state.SelectorView)
+	m.collapsedGroups = make(map[string]bool)
+	for _, key := range state.Collapsed {
+		m.collapsedGroups[key] = true
+	}
+}
+
+// saveUIState remembers the selector grouping and collapsed groups for the
+// next session
+func (m model) saveUIState() {
+	state := uiState{SelectorView: m.selectorGrouping.String()}
+	for key := range m.collapsedGroups {
+		state.Collapsed = append(state.Collapsed, key)
+	}
+	sort.Strings(state.Collapsed)
+	if err := saveUIState(state); err != nil {
+		logrus.Warnf("Failed to save UI state: %v", err)
+	}
+}
+
+// updateSelector handles key presses while the cheatsheet selector is shown
+func (m model) updateSelector(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Handle selector search input
+	if m.sheetSearchMode {
+		switch {
+		case key.Matches(msg, keys.Enter):
+			// Exit search mode (filtering already done live)
+			m.sheetSearchMode = false
+		case key.Matches(msg, keys.Back):
+			// Cancel search
+			m.sheetSearchMode = false
+			m.sheetQuery = ""
+			m.applySheetFilter()
+		case msg.Type == tea.KeyBackspace:
+			if len(m.sheetQuery) > 0 {
+				m.sheetQuery = m.sheetQuery[:len(m.sheetQuery)-1]
+			}
+			m.applySheetFilter()
+		case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+			m.sheetQuery += string(msg.Runes)
+			m.applySheetFilter()
+		}
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, keys.Search):
+		m.sheetSearchMode = true
+		m.sheetQuery = ""
+		m.applySheetFilter()
+	case key.Matches(msg, keys.Back):
+		if m.sheetQuery != "" {
+			// Clear the search and list every sheet again
+			m.sheetQuery = ""
+			m.applySheetFilter()
+		}
+	case key.Matches(msg, keys.Up):
+		if m.currentCheatsheet > 0 {
+			m.selectCheatsheet(m.currentCheatsheet - 1)
+		}
+	case key.Matches(msg, keys.Down):
+		if m.currentCheatsheet < len(m.selectorRows)-1 {
+			m.selectCheatsheet(m.currentCheatsheet + 1)
+		}
+	case key.Matches(msg, keys.Left):
+		// Collapse the group under the cursor, or the one containing it
+		if len(m.selectorRows) > 0 {
+			idx := m.currentCheatsheet
+			if row := m.selectorRows[idx]; !row.isGroup() || row.collapsed {
+				idx = m.parentGroup(idx)
+			}
+			if idx >= 0 {
+				m.setGroupCollapsed(idx, true)
+			}
+		}
+	case key.Matches(msg, keys.Right):
+		if len(m.selectorRows) > 0 {
+			m.setGroupCollapsed(m.currentCheatsheet, false)
+		}
+	case key.Matches(msg, keys.Grouping):
+		m.selectorGrouping = m.selectorGrouping.Next()
+		m.rebuildSelectorRows()
+		m.saveUIState()
+	case key.Matches(msg, keys.Enter):
+		// Load the selected cheatsheet, or fold the selected group
+		return m, m.activateSelectorRow()
+	}
+	return m, nil
+}
+
+// showPreview reports whether the terminal is wide enough for the preview
+// pane next to the cheatsheet list
+func (m model) showPreview() bool {
+	return m.width >= previewMinWidth
+}
+
+// selectorListWidth returns the width of the cheatsheet list, leaving the
+// rest of the screen to the preview pane
+func (m model) selectorListWidth() int {
+	if !m.showPreview() {
+		return m.width
+	}
+	return m.width / 2
+}
+
+// selectorView renders the cheatsheet list with the preview of the selected
+// sheet beside it
+func (m model) selectorView() string {
+	if !m.showPreview() {
+		return m.viewport.View()
+	}
+
+	list := m.viewport
+	list.Width = m.selectorListWidth()
+
+	var preview string
+	if len(m.selectorRows) > 0 {
+		row := m.selectorRows[m.currentCheatsheet]
+		if row.isGroup() {
+			preview = RenderGroupPreview(row, m.width-list.Width, m.viewport.Height)
+		} else {
+			preview = RenderSheetPreview(row.sheet, m.width-list.Width, m.viewport.Height)
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, list.View(), preview)
+}
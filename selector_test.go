@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLoadSheetInfos(t *testing.T) {
+	paths, err := DiscoverCheatsheets("cheatsheets")
+	if err != nil {
+		t.Fatalf("Failed to discover cheatsheets: %v", err)
+	}
+	infos := loadSheetInfos("cheatsheets", paths)
+	if len(infos) != len(paths) {
+		t.Fatalf("Expected %d infos, got %d", len(paths), len(infos))
+	}
+
+	for i, info := range infos {
+		// Results keep the discovery order despite loading concurrently
+		if info.Path != paths[i] {
+			t.Errorf("Expected info %d for %s, got %s", i, paths[i], info.Path)
+		}
+		if info.Path == "kubectl.yaml" {
+			if info.Title != "kubectl Cheat Sheet" || info.Category != "DevOps" {
+				t.Errorf("Unexpected kubectl metadata: %+v", info)
+			}
+			if info.CommandCount == 0 || len(info.TopTags) == 0 {
+				t.Errorf("Expected kubectl commands and tags, got %+v", info)
+			}
+		}
+	}
+}
+
+func TestTopTags(t *testing.T) {
+	commands := []Command{
+		{Tags: []string{"b", "a"}},
+		{Tags: []string{"b", "c"}},
+		{Tags: []string{"b", "c", "d"}},
+	}
+	got := topTags(commands, 3)
+	want := []string{"b", "c", "a"}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestFilterSheets(t *testing.T) {
+	infos := []sheetInfo{
+		{Path: "git.yaml", Title: "Git Commands", Category: "Developer Tools"},
+		{Path: "kubectl.yaml", Title: "kubectl Cheat Sheet", Category: "DevOps", Description: "Kubernetes command-line tool"},
+		{Path: "databases/mongo.yaml"},
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"mongo", 1},      // file name
+		{"GIT COMM", 1},   // title
+		{"devops", 1},     // category
+		{"kubernetes", 1}, // description
+		{"databases/", 1},
+		{"nothing", 0},
+	}
+	for _, tt := range tests {
+		if got := filterSheets(infos, tt.query); len(got) != tt.want {
+			t.Errorf("filterSheets(%q) returned %d sheets, want %d", tt.query, len(got), tt.want)
+		}
+	}
+}
+
+func TestSelectorSearch(t *testing.T) {
+	var tm tea.Model = initialModelWithSelector("cheatsheets")
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	tm, _ = tm.Update(loadCheatsheetsMsg("cheatsheets"))
+	m := tm.(model)
+
+	m = press(m, runes("/"))
+	m = press(m, runes("kubernetes"))
+	m = press(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.cheatsheets) != 1 || m.cheatsheets[0].Path != "kubectl.yaml" {
+		t.Fatalf("Expected only kubectl.yaml to match, got %v", m.cheatsheets)
+	}
+
+	// Enter on the match loads it
+	tm, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatalf("Expected a command loading the selected sheet")
+	}
+	tm, _ = tm.Update(cmd())
+	if got := tm.(model).cheatSheet.Title; got != "kubectl Cheat Sheet" {
+		t.Errorf("Expected kubectl to load, got %q", got)
+	}
+
+	// Esc clears the search again
+	m = press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if len(m.cheatsheets) != len(m.allCheatsheets) {
+		t.Errorf("Expected every sheet listed after Esc, got %d of %d", len(m.cheatsheets), len(m.allCheatsheets))
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// sheetFormat reads and writes cheatsheets in one file format. A file may
+// hold several sheets, as multi-document YAML does.
+type sheetFormat struct {
+	name       string
+	extensions []string // lower case, with the leading dot
+	decode     func(data []byte) ([]CheatSheet, error)
+	encode     func(sheets []CheatSheet) ([]byte, error)
+	// commandLine finds the line a command starts on, for opening $EDITOR
+	// there; nil when the format can't tell
+	commandLine func(data []byte, doc int, name string) int
+	// isSheet tells a sheet from other files with the format's extensions
+	// when looking for sheets in a directory; nil when every file is one
+	isSheet func(data []byte) bool
+}
+
+// sheetFormats is the registry of supported formats, picked by file
+// extension. The first one is used for files with any other extension.
+var sheetFormats = []sheetFormat{
+	{name: "yaml", extensions: []string{".yaml", ".yml"}, decode: decodeYAMLSheets, encode: encodeYAMLSheets, commandLine: commandLine},
+	// JSON parses as YAML, so its commands are found the same way
+	{name: "json", extensions: []string{".json"}, decode: decodeJSONSheets, encode: encodeJSONSheets, commandLine: commandLine},
+	{name: "toml", extensions: []string{".toml"}, decode: decodeTOMLSheets, encode: encodeTOMLSheets},
+	{name: "markdown", extensions: []string{".md", ".markdown"}, decode: decodeMarkdownSheets, encode: encodeMarkdownSheets, commandLine: markdownCommandLine, isSheet: hasFrontMatter},
+}
+
+// formatForPath returns the format of the file at path, judged by its
+// extension. ok is false when the extension isn't registered.
+func formatForPath(path string) (format sheetFormat, ok bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range sheetFormats {
+		if containsString(f.extensions, ext) {
+			return f, true
+		}
+	}
+	return sheetFormats[0], false
+}
+
+// formatByName returns the registered format called name
+func formatByName(name string) (sheetFormat, bool) {
+	for _, f := range sheetFormats {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return sheetFormat{}, false
+}
+
+// formatNames lists the registered formats for usage messages
+func formatNames() string {
+	var names []string
+	for _, f := range sheetFormats {
+		names = append(names, f.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// decodeYAMLSheets decodes every document of a YAML stream as a sheet
+func decodeYAMLSheets(data []byte) ([]CheatSheet, error) {
+	var sheets []CheatSheet
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var sheet CheatSheet
+		err := dec.Decode(&sheet)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, documentError(len(sheets), err)
+		}
+		sheets = append(sheets, sheet)
+	}
+	if len(sheets) == 0 {
+		// An empty file is an empty sheet
+		sheets = append(sheets, CheatSheet{})
+	}
+	return sheets, nil
+}
+
+// encodeYAMLSheets writes sheets as a YAML stream in the layout of
+// "cheatcheat fmt", one document per sheet
+func encodeYAMLSheets(sheets []CheatSheet) ([]byte, error) {
+	var b bytes.Buffer
+	for i, sheet := range sheets {
+		data, err := yaml.Marshal(sheet)
+		if err != nil {
+			return nil, err
+		}
+		formatted, err := formatSheet(data)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		b.Write(formatted)
+	}
+	return b.Bytes(), nil
+}
+
+// decodeJSONSheets decodes a JSON sheet, or an array of them
+func decodeJSONSheets(data []byte) ([]CheatSheet, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var sheets []CheatSheet
+		err := json.Unmarshal(data, &sheets)
+		return sheets, err
+	}
+	var sheet CheatSheet
+	err := json.Unmarshal(data, &sheet)
+	return []CheatSheet{sheet}, err
+}
+
+// encodeJSONSheets writes a single sheet as an object and several as an array
+func encodeJSONSheets(sheets []CheatSheet) ([]byte, error) {
+	var v any = sheets
+	if len(sheets) == 1 {
+		v = sheets[0]
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func decodeTOMLSheets(data []byte) ([]CheatSheet, error) {
+	var sheet CheatSheet
+	_, err := toml.Decode(string(data), &sheet)
+	return []CheatSheet{sheet}, err
+}
+
+func encodeTOMLSheets(sheets []CheatSheet) ([]byte, error) {
+	if len(sheets) != 1 {
+		return nil, fmt.Errorf("a TOML file holds one sheet, got %d", len(sheets))
+	}
+	var b bytes.Buffer
+	err := toml.NewEncoder(&b).Encode(sheets[0])
+	return b.Bytes(), err
+}
+
+// documentError names the document of a multi-document file an error is in
+func documentError(doc int, err error) error {
+	if doc == 0 {
+		return err
+	}
+	return fmt.Errorf("document %d: %w", doc+1, err)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// formatFixtures holds the same sheet in every registered format
+var formatFixtures = map[string]string{
+	"sheet.yml": `title: Archives
+category: Tools
+commands:
+  - name: tar
+    tags: [archive]
+    complexity: Beginner
+    examples:
+      - code: tar -xf a.tar
+        description: Extract
+`,
+	"sheet.json": `{
+  "title": "Archives",
+  "category": "Tools",
+  "commands": [
+    {"name": "tar", "tags": ["archive"], "complexity": "Beginner",
+     "examples": [{"code": "tar -xf a.tar", "description": "Extract"}]}
+  ]
+}
+`,
+	"sheet.toml": `title = "Archives"
+category = "Tools"
+
+[[commands]]
+name = "tar"
+tags = ["archive"]
+complexity = "Beginner"
+
+  [[commands.examples]]
+  code = "tar -xf a.tar"
+  description = "Extract"
+`,
+	"sheet.md": `---
+title: Archives
+category: Tools
+commands:
+  - name: tar
+    tags: [archive]
+    complexity: Beginner
+    examples:
+      - code: tar -xf a.tar
+        description: Extract
+---
+`,
+}
+
+func writeFixtures(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadSheetFormats(t *testing.T) {
+	dir := writeFixtures(t, formatFixtures)
+	want := CheatSheet{
+		Title:    "Archives",
+		Category: "Tools",
+		Commands: []Command{{
+			Name:       "tar",
+			Tags:       []string{"archive"},
+			Complexity: "beginner",
+			Examples:   []Example{{Code: "tar -xf a.tar", Description: "Extract"}},
+		}},
+	}
+
+	for name := range formatFixtures {
+		t.Run(name, func(t *testing.T) {
+			sheets, err := LoadCheatSheets(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sheets) != 1 {
+				t.Fatalf("Expected one sheet, got %d", len(sheets))
+			}
+			got := sheets[0]
+			got.Path = ""
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestDiscoverSheetFormats(t *testing.T) {
+	files := map[string]string{
+		"notes.txt": "not a sheet",
+		"README.md": "# My sheets\n\n## Installing\n\nCopy them over.\n",
+	}
+	for name, text := range formatFixtures {
+		files[name] = text
+	}
+	dir := writeFixtures(t, files)
+
+	got, err := DiscoverCheatsheets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"sheet.json", "sheet.md", "sheet.toml", "sheet.yml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestMultiDocumentSheets(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"tools.yaml": "title: First\ncommands:\n  - name: one\n---\ntitle: Second\ncommands:\n  - name: two\n  - name: three\n",
+		"bad.yaml":   "title: Fine\n---\ncommands:\n  - name: x\n    complexity: hard\n",
+	})
+
+	sheets, err := LoadCheatSheets(filepath.Join(dir, "tools.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 2 || sheets[1].Title != "Second" || sheets[1].Document != 1 {
+		t.Fatalf("Expected two sheets, got %+v", sheets)
+	}
+	second, err := loadCheatSheetAt(filepath.Join(dir, "tools.yaml"), 1)
+	if err != nil || len(second.Commands) != 2 {
+		t.Errorf("Expected the second document with 2 commands, got %+v, %v", second, err)
+	}
+
+	infos := loadSheetInfos(dir, []string{"bad.yaml", "tools.yaml"})
+	if len(infos) != 3 {
+		t.Fatalf("Expected an entry per document and one for the broken file, got %+v", infos)
+	}
+	if !strings.Contains(infos[0].Err.Error(), "document 2") {
+		t.Errorf("Expected the error to name the document, got %v", infos[0].Err)
+	}
+	if infos[1].Name() != "tools.yaml#1" || infos[2].Name() != "tools.yaml#2" {
+		t.Errorf("Expected tools.yaml#1 and tools.yaml#2, got %s and %s", infos[1].Name(), infos[2].Name())
+	}
+
+	if _, err := loadSheetDocument(filepath.Join(dir, "tools.yaml")); err == nil {
+		t.Error("Expected editing a multi-document file in place to be refused")
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	dir := t.TempDir()
+	original, err := LoadCheatSheet("cheatsheets/git.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// YAML to each format and back again
+	for _, name := range []string{"git.json", "git.toml", "git.md"} {
+		var stdout, stderr bytes.Buffer
+		path := filepath.Join(dir, name)
+		if status := runConvert([]string{"-o", path, "cheatsheets/git.yaml"}, &stdout, &stderr); status != 0 {
+			t.Fatalf("convert to %s failed with status %d: %s", name, status, stderr.String())
+		}
+		back := filepath.Join(dir, name+".yaml")
+		if status := runConvert([]string{"-o", back, path}, &stdout, &stderr); status != 0 {
+			t.Fatalf("convert from %s failed with status %d: %s", name, status, stderr.String())
+		}
+		sheet, err := LoadCheatSheet(back)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet.Path = original.Path
+		// Compare as JSON, which doesn't tell empty lists from missing ones
+		got, _ := encodeJSONSheets([]CheatSheet{sheet})
+		want, _ := encodeJSONSheets([]CheatSheet{original})
+		if !bytes.Equal(got, want) {
+			t.Errorf("Expected %s to convert back to the same sheet", name)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := runConvert([]string{"--to", "json", "cheatsheets/git.yaml"}, &stdout, &stderr); status != 0 || !strings.HasPrefix(stdout.String(), "{") {
+		t.Errorf("Expected JSON on stdout, got status %d: %.40q", status, stdout.String())
+	}
+	if status := runConvert([]string{"--to", "xml", "cheatsheets/git.yaml"}, &stdout, &stderr); status != 2 {
+		t.Errorf("Expected an unknown format to be a usage error, got status %d", status)
+	}
+	if status := runConvert([]string{"cheatsheets/git.yaml"}, &stdout, &stderr); status != 2 {
+		t.Errorf("Expected a missing output format to be a usage error, got status %d", status)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// uiState is what the TUI remembers between sessions
+type uiState struct {
+	SelectorView string   `json:"selectorView,omitempty"` // grouping of the cheatsheet selector
+	Collapsed    []string `json:"collapsed,omitempty"`    // keys of collapsed selector groups
+}
+
+// stateDir returns the directory cheatcheat keeps its local state in,
+// following the XDG base directory spec
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "cheatcheat"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "cheatcheat"), nil
+}
+
+// loadUIState reads the saved UI state. A missing state file is not an error
+// and yields the zero state.
+func loadUIState() (uiState, error) {
+	var state uiState
+	dir, err := stateDir()
+	if err != nil {
+		return state, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "ui.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// saveUIState writes the UI state for the next session
+func saveUIState(state uiState) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "ui.json"), data, 0644)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// validateSheet checks a freshly decoded sheet for values the rest of the
+// program can't make sense of, normalizing the ones it can. All problems are
+// reported together.
+func validateSheet(sheet *CheatSheet) error {
+	var errs []error
+	for i := range sheet.Commands {
+		cmd := &sheet.Commands[i]
+		errs = append(errs, validateConstraints(*cmd)...)
+		if cmd.Complexity == "" {
+			continue
+		}
+		level, ok := parseComplexity(cmd.Complexity)
+		if !ok {
+			errs = append(errs, fmt.Errorf("command %q: unknown complexity %q (want beginner, intermediate or advanced)",
+				cmd.Name, cmd.Complexity))
+			continue
+		}
+		cmd.Complexity = level.String()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// In sheets that declare vars, and in every sheet when variables are set in
+// the config file, the environment or with --var, the syntax, example code
+// and notes of commands are templates: text that refers to a variable as
+// {{ .name }} is expanded with text/template as it is shown, so {{ .registry }}
+// stands for the value of the registry variable and variables without a value
+// are highlighted. A literal {{ is written as {{"{{"}}. Text without such
+// references, and every sheet when no variables apply, is shown as written.
+
+// varEnvPrefix starts the names of environment variables that set sheet
+// variables, as in CHEATCHEAT_VAR_registry=ghcr.io/acme
+const varEnvPrefix = "CHEATCHEAT_VAR_"
+
+// varOverrides holds the variable values from the config file, the
+// environment and --var, which take precedence over the sheets' own
+var varOverrides map[string]string
+
+// unresolvedMark brackets the names of variables without a value in expanded
+// text, for the renderer to highlight them
+const unresolvedMark = "\x00"
+
+// varFlag collects repeated --var name=value flags
+type varFlag map[string]string
+
+func (v varFlag) String() string {
+	var pairs []string
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v varFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// overrideVars layers the variables set in the config file, the environment
+// (as KEY=value pairs, like os.Environ) and on the command line, later ones
+// winning
+func overrideVars(configured map[string]string, environ []string, flags map[string]string) map[string]string {
+	vars := make(map[string]string)
+	for name, value := range configured {
+		vars[name] = value
+	}
+	for _, pair := range environ {
+		if name, value, ok := strings.Cut(pair, "="); ok && strings.HasPrefix(name, varEnvPrefix) && len(name) > len(varEnvPrefix) {
+			vars[strings.TrimPrefix(name, varEnvPrefix)] = value
+		}
+	}
+	for name, value := range flags {
+		vars[name] = value
+	}
+	return vars
+}
+
+// sheetVars returns the variables to expand the commands of sheet with: its
+// own vars under the overrides. It is nil when the sheet declares no vars and
+// there are no overrides, leaving the sheet's text as it is.
+func sheetVars(sheet CheatSheet) map[string]string {
+	if sheet.Vars == nil && len(varOverrides) == 0 {
+		return nil
+	}
+	vars := make(map[string]string)
+	for name, value := range sheet.Vars {
+		vars[name] = value
+	}
+	for name, value := range varOverrides {
+		vars[name] = value
+	}
+	return vars
+}
+
+// parseTemplate parses text as a template and lists the variables it refers
+// to as {{ .name }}. isTemplate tells whether text is meant as one: whether it
+// refers to a variable or writes literal braces as {{"{{"}}.
+func parseTemplate(text string) (tmpl *template.Template, names []string, isTemplate bool, err error) {
+	tmpl, err = template.New("").Parse(text)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	seen := make(map[string]bool)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			if isEscape(n) {
+				isTemplate = true
+			}
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, cmd := range n.Cmds {
+					walk(cmd)
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	if tmpl.Tree != nil {
+		walk(tmpl.Tree.Root)
+	}
+	return tmpl, names, isTemplate || len(names) > 0, nil
+}
+
+// isEscape reports whether action only writes a string, as {{"{{"}} does
+func isEscape(action *parse.ActionNode) bool {
+	pipe := action.Pipe
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	_, ok := pipe.Cmds[0].Args[0].(*parse.StringNode)
+	return ok
+}
+
+// unresolvedVars lists the variables text refers to that have no value. A nil
+// vars, where no variables apply, leaves nothing unresolved.
+func unresolvedVars(text string, vars map[string]string) ([]string, error) {
+	if vars == nil || !strings.Contains(text, "{{") {
+		return nil, nil
+	}
+	_, names, _, err := parseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, name := range names {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// expandVars executes text as a template over vars. Variables without a
+// value are left in between unresolvedMarks. Text that isn't a valid template,
+// or doesn't refer to variables, is returned as it is.
+func expandVars(text string, vars map[string]string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, names, isTemplate, err := parseTemplate(text)
+	if err != nil || !isTemplate {
+		return text
+	}
+	data := make(map[string]string)
+	for name, value := range vars {
+		data[name] = value
+	}
+	for _, name := range names {
+		if _, ok := data[name]; !ok {
+			data[name] = unresolvedMark + name + unresolvedMark
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return text
+	}
+	return b.String()
+}
+
+// expandCommand returns cmd with vars expanded into its syntax, example code
+// and notes. A nil vars leaves cmd as it is.
+func expandCommand(cmd Command, vars map[string]string) Command {
+	if vars == nil {
+		return cmd
+	}
+	cmd.Syntax = expandVars(cmd.Syntax, vars)
+	cmd.Examples = append([]Example(nil), cmd.Examples...)
+	for i := range cmd.Examples {
+		cmd.Examples[i].Code = expandVars(cmd.Examples[i].Code, vars)
+	}
+	cmd.Notes = append([]string(nil), cmd.Notes...)
+	for i := range cmd.Notes {
+		cmd.Notes[i] = expandVars(cmd.Notes[i], vars)
+	}
+	return cmd
+}
+
+// renderVars renders text in style, highlighting the unresolved variables
+// marked in it. Pieces are styled on their own, line by line, so the
+// highlight doesn't cut the surrounding style short.
+func renderVars(text string, style lipgloss.Style) string {
+	if !strings.Contains(text, unresolvedMark) {
+		return style.Render(text)
+	}
+
+	inner := style.UnsetPadding()
+	highlight := unresolvedVarStyle.Background(style.GetBackground())
+	pad := func(n int) string {
+		return lipgloss.NewStyle().Background(style.GetBackground()).Render(strings.Repeat(" ", n))
+	}
+
+	lines := strings.Split(text, "\n")
+	widths := make([]int, len(lines))
+	width := 0
+	for i, line := range lines {
+		for j, part := range strings.Split(line, unresolvedMark) {
+			if j%2 == 1 {
+				part = unresolvedVar(part)
+			}
+			widths[i] += lipgloss.Width(part)
+		}
+		width = max(width, widths[i])
+	}
+
+	var rendered []string
+	for i, line := range lines {
+		var b strings.Builder
+		b.WriteString(pad(style.GetPaddingLeft()))
+		for j, part := range strings.Split(line, unresolvedMark) {
+			switch {
+			case j%2 == 1:
+				b.WriteString(highlight.Render(unresolvedVar(part)))
+			case part != "":
+				b.WriteString(inner.Render(part))
+			}
+		}
+		b.WriteString(pad(width - widths[i] + style.GetPaddingRight()))
+		rendered = append(rendered, b.String())
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// unresolvedVar shows a variable without a value the way it is written
+func unresolvedVar(name string) string {
+	return "{{ ." + name + " }}"
+}
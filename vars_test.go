@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// withVarOverrides sets varOverrides for the length of a test
+func withVarOverrides(t *testing.T, vars map[string]string) {
+	t.Helper()
+	saved := varOverrides
+	varOverrides = vars
+	t.Cleanup(func() { varOverrides = saved })
+}
+
+func TestOverrideVars(t *testing.T) {
+	flags := make(varFlag)
+	if err := flags.Set("tag=v2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flags.Set("novalue"); err == nil {
+		t.Error("Expected a flag without = to be rejected")
+	}
+
+	got := overrideVars(
+		map[string]string{"registry": "config.io", "tag": "config", "ns": "config"},
+		[]string{"CHEATCHEAT_VAR_tag=env", "CHEATCHEAT_VAR_ns=env", "HOME=/root", "CHEATCHEAT_VAR_="},
+		flags,
+	)
+	want := map[string]string{"registry": "config.io", "tag": "v2", "ns": "env"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if cfg, err := loadConfig(); err != nil || cfg.Vars != nil {
+		t.Errorf("Expected no config without a file, got %+v, %v", cfg, err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "cheatcheat"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cheatcheat", "config.yaml"), []byte("vars:\n  registry: ghcr.io/acme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig()
+	if err != nil || cfg.Vars["registry"] != "ghcr.io/acme" {
+		t.Errorf("Expected the registry variable, got %+v, %v", cfg, err)
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	withVarOverrides(t, map[string]string{"tag": "v2"})
+	sheet := CheatSheet{Vars: map[string]string{"registry": "ghcr.io/acme", "tag": "latest"}}
+	cmd := Command{
+		Syntax:   "docker pull {{ .registry }}/<image>",
+		Examples: []Example{{Code: "docker run {{ .registry }}/app:{{ .tag }} -n {{ .namespace }}"}},
+		Notes:    []string{"Broken {{ .registry", "Plain"},
+	}
+
+	got := expandCommand(cmd, sheetVars(sheet))
+	if got.Syntax != "docker pull ghcr.io/acme/<image>" {
+		t.Errorf("Unexpected syntax %q", got.Syntax)
+	}
+	if want := "docker run ghcr.io/acme/app:v2 -n " + unresolvedMark + "namespace" + unresolvedMark; got.Examples[0].Code != want {
+		t.Errorf("Expected %q, got %q", want, got.Examples[0].Code)
+	}
+	if !reflect.DeepEqual(got.Notes, cmd.Notes) {
+		t.Errorf("Expected a template that doesn't parse to be left alone, got %q", got.Notes)
+	}
+	if cmd.Examples[0].Code == got.Examples[0].Code {
+		t.Error("Expected the command's own examples to be left unexpanded")
+	}
+
+	// A misspelled variable is marked like any other without a value
+	typo := Command{Syntax: "kubectl get pods -n {{ .namspace }}"}
+	if got := expandCommand(typo, sheetVars(sheet)); got.Syntax != "kubectl get pods -n "+unresolvedMark+"namspace"+unresolvedMark {
+		t.Errorf("Expected the misspelled variable marked, got %q", got.Syntax)
+	}
+
+	// Literal braces are escaped in sheets with variables, and text without
+	// any is shown as written
+	escaped := Command{Syntax: `docker ps --format '{{"{{"}}.Names}}'`}
+	if got := expandCommand(escaped, sheetVars(sheet)); got.Syntax != `docker ps --format '{{.Names}}'` {
+		t.Errorf("Expected the escaped braces kept, got %q", got.Syntax)
+	}
+	literal := Command{Syntax: `docker ps --format "{{.Names}}"`, Notes: []string{"Plain"}}
+	withVarOverrides(t, nil)
+	if got := expandCommand(literal, sheetVars(CheatSheet{})); !reflect.DeepEqual(got, literal) {
+		t.Errorf("Expected a sheet without variables left alone, got %+v", got)
+	}
+	withVarOverrides(t, map[string]string{"tag": "v2"})
+
+	// The overrides apply to sheets without vars of their own
+	if got := expandCommand(Command{Syntax: "docker pull app:{{ .tag }}"}, sheetVars(CheatSheet{})); got.Syntax != "docker pull app:v2" {
+		t.Errorf("Expected the override expanded in a sheet without vars, got %q", got.Syntax)
+	}
+}
+
+func TestRenderUnresolvedVars(t *testing.T) {
+	cmd := expandCommand(Command{Syntax: "kubectl --context {{ .ctx }} -n {{ .namespace }} get pods\nkubectl get nodes"}, map[string]string{"ctx": "prod"})
+	detail := RenderCommandDetail(cmd, false)
+	if strings.Contains(detail, unresolvedMark) {
+		t.Error("Expected the marks to be replaced in the rendered detail")
+	}
+	plain := ansi.Strip(detail)
+	if !strings.Contains(plain, "  kubectl --context prod -n {{ .namespace }} get pods  \n  kubectl get nodes                                   ") {
+		t.Errorf("Expected the variable shown as written in an evenly padded block, got:\n%s", plain)
+	}
+}
+
+func TestVarsInherited(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"base.yaml": "vars:\n  registry: docker.io\n  tag: latest\ncommands:\n  - name: pull\n    syntax: docker pull {{ .registry }}/app:{{ .tag }}\n",
+		"prod.yaml": "extends: base.yaml\nvars:\n  registry: ghcr.io/acme\n",
+	})
+	sheet, err := LoadCheatSheet(filepath.Join(dir, "prod.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"registry": "ghcr.io/acme", "tag": "latest"}
+	if !reflect.DeepEqual(sheet.Vars, want) {
+		t.Errorf("Expected vars %v, got %v", want, sheet.Vars)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	withVarOverrides(t, nil)
+	dir := writeFixtures(t, map[string]string{
+		"deploy.yaml": `vars:
+  registry: ghcr.io/acme
+commands:
+  - name: push
+    syntax: docker push {{ .registry }}/app:{{ .tag }}
+    notes:
+      - "Broken {{ .registry"
+  - name: ps
+    syntax: docker ps --format '{{"{{"}}.Names}}'
+  - name: get pods
+    syntax: kubectl get pods -n {{ .namspace }}
+`,
+		"docker.yaml": "commands:\n  - name: ps\n    syntax: docker ps --format '{{.Names}}'\n  - name: pull\n    syntax: docker pull {{ .registry }}/app:{{ .tag }}\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	if status := runLint([]string{dir}, &stdout, &stderr); status != 1 {
+		t.Errorf("Expected lint to fail, got status %d", status)
+	}
+	out := stdout.String()
+	path := filepath.Join(dir, "deploy.yaml")
+	for _, want := range []string{
+		path + `: command "push": syntax: unresolved variable "tag"`,
+		path + `: command "push": note 1: template:`,
+		path + `: command "get pods": syntax: unresolved variable "namspace"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "docker.yaml") || strings.Contains(out, `"ps"`) {
+		t.Errorf("Expected escaped braces, and sheets without variables, to pass, got:\n%s", out)
+	}
+
+	// Overrides make templates of sheets without vars too
+	stdout.Reset()
+	runLint([]string{"--var", "registry=ghcr.io/acme", filepath.Join(dir, "docker.yaml")}, &stdout, &stderr)
+	if want := `docker.yaml: command "pull": syntax: unresolved variable "tag"`; !strings.Contains(stdout.String(), want) {
+		t.Errorf("Expected %q in:\n%s", want, stdout.String())
+	}
+
+	stdout.Reset()
+	runLint([]string{"--var", "tag=v1", "--var", "namspace=prod", path}, &stdout, &stderr)
+	if strings.Contains(stdout.String(), "unresolved") {
+		t.Errorf("Expected --var to resolve tag, got:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if status := runLint([]string{"cheatsheets"}, &stdout, &stderr); status != 0 {
+		t.Errorf("Expected the bundled sheets to pass, got:\n%s%s", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Verification opts an example in to "cheatcheat verify", which runs its code
+// and checks what happens
+type Verification struct {
+	Setup             string `yaml:"setup,omitempty" json:"setup,omitempty" toml:"setup,omitempty"`                                     // shell script run first, to prepare the sandbox
+	ExpectExit        *int   `yaml:"expectExit,omitempty" json:"expectExit,omitempty" toml:"expectExit,omitempty"`                      // status the code must exit with; the example's exitCode when unset
+	ExpectOutputRegex string `yaml:"expectOutputRegex,omitempty" json:"expectOutputRegex,omitempty" toml:"expectOutputRegex,omitempty"` // pattern the combined output must match
+}
+
+// verifyShell runs the setup and code of verified examples
+var verifyShell = "sh"
+
+// verifyResult is the outcome of verifying one example
+type verifyResult struct {
+	Command  string        // name of the command the example belongs to
+	Example  int           // 1-based number of the example within the command
+	Duration time.Duration // how long setup and code took
+	Failure  string        // why the example failed, empty when it passed
+	Output   string        // what the code printed, kept for failures
+}
+
+// name describes the example in reports
+func (r verifyResult) name() string {
+	return fmt.Sprintf("%s: example %d", r.Command, r.Example)
+}
+
+// sandboxEnv is the environment verified examples run in: the PATH to find
+// tools and a home directory inside the sandbox, nothing else from the user
+func sandboxEnv(dir string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LC_ALL=C",
+		"TERM=dumb",
+	}
+}
+
+// runSandboxed runs script with the shell in dir, returning what it printed
+// and its exit status
+func runSandboxed(ctx context.Context, dir, script string) (string, int, error) {
+	cmd := exec.CommandContext(ctx, verifyShell, "-c", script)
+	cmd.Dir = dir
+	cmd.Env = sandboxEnv(dir)
+	cmd.WaitDelay = time.Second
+	isolateProcess(cmd)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return string(out), -1, ctx.Err()
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return string(out), exit.ExitCode(), nil
+	}
+	return string(out), 0, err
+}
+
+// verifyExample runs the setup and code of ex in a fresh temporary directory
+// and checks its exit status and output. vars expand templates in the code.
+func verifyExample(cmd Command, i int, vars map[string]string, timeout time.Duration) (result verifyResult) {
+	ex := cmd.Examples[i]
+	result = verifyResult{Command: cmd.Name, Example: i + 1}
+	// Named, so the deferred call sets the Duration of what is returned
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	code := expandCommand(cmd, vars).Examples[i].Code
+	if strings.Contains(code, unresolvedMark) {
+		result.Failure = "code has unresolved variables"
+		return result
+	}
+	want := ex.ExitCode
+	if ex.Verify.ExpectExit != nil {
+		want = *ex.Verify.ExpectExit
+	}
+	var pattern *regexp.Regexp
+	if ex.Verify.ExpectOutputRegex != "" {
+		var err error
+		if pattern, err = regexp.Compile(ex.Verify.ExpectOutputRegex); err != nil {
+			result.Failure = fmt.Sprintf("expectOutputRegex: %v", err)
+			return result
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "cheatcheat-verify-")
+	if err != nil {
+		result.Failure = err.Error()
+		return result
+	}
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if ex.Verify.Setup != "" {
+		out, status, err := runSandboxed(ctx, dir, ex.Verify.Setup)
+		if err != nil || status != 0 {
+			result.Failure, result.Output = sandboxFailure("setup", status, err, timeout), out
+			return result
+		}
+	}
+	out, status, err := runSandboxed(ctx, dir, code)
+	result.Output = out
+	switch {
+	case err != nil:
+		result.Failure = sandboxFailure("code", status, err, timeout)
+	case status != want:
+		result.Failure = fmt.Sprintf("exited with %d, want %d", status, want)
+	case pattern != nil && !pattern.MatchString(out):
+		result.Failure = fmt.Sprintf("output doesn't match %q", pattern)
+	}
+	return result
+}
+
+// sandboxFailure words why setup or code failed to run to a zero status
+func sandboxFailure(what string, status int, err error, timeout time.Duration) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("%s timed out after %s", what, timeout)
+	case err != nil:
+		return fmt.Sprintf("%s: %v", what, err)
+	}
+	return fmt.Sprintf("%s exited with %d", what, status)
+}
+
+// verifySheet verifies every example of sheet that opts in
+func verifySheet(sheet CheatSheet, timeout time.Duration) []verifyResult {
+	var results []verifyResult
+	vars := sheetVars(sheet)
+	for _, cmd := range sheet.Commands {
+		for i, ex := range cmd.Examples {
+			if ex.Verify != nil {
+				results = append(results, verifyExample(cmd, i, vars, timeout))
+			}
+		}
+	}
+	return results
+}
+
+// verifiedSheet is the results of one sheet, named by its file
+type verifiedSheet struct {
+	name    string
+	results []verifyResult
+}
+
+// runVerify implements "cheatcheat verify": it runs the examples that opt in
+// with verify, each in its own temporary directory, and reports them per
+// sheet and command as text or JUnit XML. It exits non-zero when any fails.
+func runVerify(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "report format: text or junit")
+	timeout := flags.Duration("timeout", 10*time.Second, "time each example may take, setup included")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cheatcheat verify [--format text|junit] [--timeout d] [paths...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "junit" {
+		fmt.Fprintf(stderr, "unknown report format %q, want text or junit\n", *format)
+		return 2
+	}
+
+	targets := flags.Args()
+	if len(targets) == 0 {
+		for _, root := range sheetRoots {
+			if _, err := os.Stat(root); err == nil {
+				targets = append(targets, root)
+			}
+		}
+	}
+	paths, err := sheetPaths(targets)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	status := 0
+	var verified []verifiedSheet
+	for _, path := range paths {
+		sheets, err := LoadCheatSheets(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 1
+			continue
+		}
+		for i, sheet := range sheets {
+			name := path
+			if len(sheets) > 1 {
+				name = fmt.Sprintf("%s#%d", path, i+1)
+			}
+			results := verifySheet(sheet, *timeout)
+			if len(results) == 0 {
+				continue
+			}
+			for _, r := range results {
+				if r.Failure != "" {
+					status = 1
+				}
+			}
+			verified = append(verified, verifiedSheet{name, results})
+		}
+	}
+
+	if *format == "junit" {
+		err = writeJUnit(stdout, verified)
+	} else {
+		writeVerifyText(stdout, verified)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return status
+}
+
+// writeVerifyText reports results a line per example, with the output of
+// failures indented below them
+func writeVerifyText(w io.Writer, verified []verifiedSheet) {
+	passed, failed := 0, 0
+	for _, sheet := range verified {
+		fmt.Fprintln(w, sheet.name)
+		for _, r := range sheet.results {
+			if r.Failure == "" {
+				passed++
+				fmt.Fprintf(w, "  ok   %s\n", r.name())
+				continue
+			}
+			failed++
+			fmt.Fprintf(w, "  FAIL %s: %s\n", r.name(), r.Failure)
+			for _, line := range strings.Split(strings.TrimRight(r.Output, "\n"), "\n") {
+				if line != "" {
+					fmt.Fprintf(w, "       | %s\n", line)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d passed, %d failed\n", passed, failed)
+}
+
+// JUnit XML, as read by CI systems: a suite per sheet, a case per example
+type (
+	junitSuites struct {
+		XMLName xml.Name     `xml:"testsuites"`
+		Suites  []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Time     string      `xml:"time,attr"`
+		Cases    []junitCase `xml:"testcase"`
+	}
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		Classname string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Output  string `xml:",chardata"`
+	}
+)
+
+// writeJUnit reports results as JUnit XML
+func writeJUnit(w io.Writer, verified []verifiedSheet) error {
+	seconds := func(d time.Duration) string { return fmt.Sprintf("%.3f", d.Seconds()) }
+	var report junitSuites
+	for _, sheet := range verified {
+		suite := junitSuite{Name: sheet.name, Tests: len(sheet.results)}
+		var total time.Duration
+		for _, r := range sheet.results {
+			total += r.Duration
+			c := junitCase{Name: r.name(), Classname: sheet.name, Time: seconds(r.Duration)}
+			if r.Failure != "" {
+				suite.Failures++
+				c.Failure = &junitFailure{Message: r.Failure, Output: r.Output}
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suite.Time = seconds(total)
+		report.Suites = append(report.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// isolateProcess leaves cmd as it is where there are no process groups; a
+// timeout kills only the shell
+func isolateProcess(cmd *exec.Cmd) {}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifySheetYAML opts examples built on coreutils in to verification, so
+// the tests run offline
+const verifySheetYAML = `title: Coreutils
+commands:
+  - name: echo
+    shortDesc: print text
+    syntax: echo [text]
+    examples:
+      - code: echo hello
+        description: say hello
+        verify:
+          expectOutputRegex: "^hello\n$"
+      - code: exit 99
+        description: not verified, so never run
+  - name: wc
+    shortDesc: count lines
+    syntax: wc -l [file]
+    examples:
+      - code: wc -l < lines.txt
+        description: count the lines of a file made by setup
+        verify:
+          setup: printf 'a\nb\n' > lines.txt
+          expectOutputRegex: '^\s*2\s*$'
+      - code: ls -A; test -z "$USER" && echo "HOME=$HOME"
+        description: an empty sandbox without the user's environment
+        verify:
+          expectOutputRegex: '^HOME=.*cheatcheat-verify-'
+  - name: cat
+    shortDesc: print files
+    syntax: cat [file]
+    examples:
+      - code: cat missing.txt
+        description: a missing file fails
+        exitCode: 1
+        verify: {}
+      - code: cat missing.txt
+        description: expecting success fails the check
+        verify:
+          expectExit: 0
+      - code: cat lines.txt
+        description: setup failing
+        verify:
+          setup: exit 3
+  - name: sleep
+    shortDesc: wait
+    syntax: sleep seconds
+    examples:
+      - code: sleep 5
+        description: too slow
+        verify:
+          expectExit: 0
+`
+
+func writeVerifySheet(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "coreutils.yaml")
+	if err := os.WriteFile(path, []byte(verifySheetYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifySheet(t *testing.T) {
+	sheets, err := LoadCheatSheets(writeVerifySheet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	results := verifySheet(sheets[0], 300*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("expected sleep 5 cut short by the timeout, took %s", elapsed)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.name()+" "+r.Failure)
+	}
+	want := []string{
+		"echo: example 1 ",
+		"wc: example 1 ",
+		"wc: example 2 ",
+		"cat: example 1 ",
+		"cat: example 2 exited with 1, want 0",
+		"cat: example 3 setup exited with 3",
+		"sleep: example 1 code timed out after 300ms",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got results\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestVerifyDuration(t *testing.T) {
+	cmd := Command{Name: "sleep", Examples: []Example{{Code: "sleep 0.2", Verify: &Verification{}}}}
+	r := verifyExample(cmd, 0, nil, time.Second)
+	if r.Failure != "" {
+		t.Fatalf("unexpected failure %s", r.Failure)
+	}
+	if r.Duration < 200*time.Millisecond {
+		t.Errorf("expected the time the example took, got %v", r.Duration)
+	}
+}
+
+func TestVerifyVars(t *testing.T) {
+	cmd := Command{Name: "echo", Examples: []Example{{Code: "echo {{ .greeting }}, {{ .name }}", Verify: &Verification{ExpectOutputRegex: "^hi, you\n$"}}}}
+	if r := verifyExample(cmd, 0, map[string]string{"greeting": "hi", "name": "you"}, time.Second); r.Failure != "" {
+		t.Errorf("expected the variables expanded, got %s", r.Failure)
+	}
+	if r := verifyExample(cmd, 0, map[string]string{"greeting": "hi"}, time.Second); r.Failure != "code has unresolved variables" {
+		t.Errorf("expected unresolved variables to fail, got %q", r.Failure)
+	}
+}
+
+func TestRunVerifyReports(t *testing.T) {
+	path := writeVerifySheet(t)
+
+	var stdout, stderr bytes.Buffer
+	if status := runVerify([]string{"--timeout", "300ms", path}, &stdout, &stderr); status != 1 {
+		t.Errorf("expected exit status 1 with failures, got %d (%s)", status, stderr.String())
+	}
+	text := stdout.String()
+	for _, want := range []string{
+		path + "\n  ok   echo: example 1\n",
+		"  FAIL cat: example 2: exited with 1, want 0\n       | cat: ",
+		"4 passed, 3 failed\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	stdout.Reset()
+	runVerify([]string{"--format", "junit", "--timeout", "300ms", path}, &stdout, &stderr)
+	var report junitSuites
+	if err := xml.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("expected JUnit XML, got %v:\n%s", err, stdout.String())
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("expected a suite for the sheet, got %d", len(report.Suites))
+	}
+	suite := report.Suites[0]
+	if suite.Name != path || suite.Tests != 7 || suite.Failures != 3 {
+		t.Errorf("got suite %q with %d tests and %d failures", suite.Name, suite.Tests, suite.Failures)
+	}
+	if c := suite.Cases[5]; c.Name != "cat: example 3" || c.Failure == nil || c.Failure.Message != "setup exited with 3" {
+		t.Errorf("expected the setup failure reported, got %+v", c)
+	}
+
+	if status := runVerify([]string{"--format", "tap", path}, &stdout, &stderr); status != 2 {
+		t.Errorf("expected a usage error for an unknown format, got %d", status)
+	}
+}
+
+func TestMarkdownVerify(t *testing.T) {
+	exit := 1
+	sheet := CheatSheet{Title: "t", Commands: []Command{{Name: "cat", Syntax: "cat", Examples: []Example{{
+		Code:   "cat missing.txt",
+		Output: "cat: missing.txt: No such file or directory",
+		Verify: &Verification{Setup: "touch other.txt", ExpectExit: &exit, ExpectOutputRegex: `No such file`},
+	}}}}}
+	data, err := encodeMarkdownSheets([]CheatSheet{sheet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "```verify\nsetup: touch other.txt\nexpectExit: 1\n") {
+		t.Errorf("expected a verify block in:\n%s", data)
+	}
+	sheets, err := decodeMarkdownSheets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sheets[0].Commands[0].Examples, sheet.Commands[0].Examples) {
+		t.Errorf("round trip changed the example: %+v", sheets[0].Commands[0].Examples[0])
+	}
+
+	_, err = decodeMarkdownSheets([]byte("## a\n### Examples\n```\nls\n```\n```verify\nsetup: [\n```\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "yaml: line 7:") {
+		t.Errorf("expected a YAML error on line 7, got %v", err)
+	}
+}